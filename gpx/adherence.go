@@ -0,0 +1,284 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"sort"
+)
+
+// AdherenceOptions contains the course adherence settings. Zero fields are
+// replaced with the values from DefaultAdherenceOptions.
+type AdherenceOptions struct {
+	// MaxDeviation is the distance (in meters) from the course above which a
+	// recorded point is off course
+	MaxDeviation float64
+	// CheckpointRadius is the distance (in meters) the recorded track must
+	// come to a checkpoint
+	CheckpointRadius float64
+	// SampleDistance is the step (in meters) used to sample the course when
+	// computing the coverage
+	SampleDistance float64
+}
+
+// DefaultAdherenceOptions are used for zero AdherenceOptions fields
+var DefaultAdherenceOptions = AdherenceOptions{
+	MaxDeviation:     50,
+	CheckpointRadius: 50,
+	SampleDistance:   10,
+}
+
+func (opts AdherenceOptions) withDefaults() AdherenceOptions {
+	if opts.MaxDeviation <= 0 {
+		opts.MaxDeviation = DefaultAdherenceOptions.MaxDeviation
+	}
+	if opts.CheckpointRadius <= 0 {
+		opts.CheckpointRadius = DefaultAdherenceOptions.CheckpointRadius
+	}
+	if opts.SampleDistance <= 0 {
+		opts.SampleDistance = DefaultAdherenceOptions.SampleDistance
+	}
+	return opts
+}
+
+// OffCourse is a stretch of consecutive recorded points farther than
+// AdherenceOptions.MaxDeviation from the course
+type OffCourse struct {
+	// Start and End are the first and last off course points
+	Start, End TrackPosition
+	// MaxDeviation is the biggest distance (in meters) from the course
+	MaxDeviation float64
+}
+
+// MissedCheckpoint is a checkpoint the recorded track didn't pass
+type MissedCheckpoint struct {
+	Checkpoint GPXPoint
+	// CourseDistance is the distance (in meters) of the checkpoint from the
+	// course start
+	CourseDistance float64
+	// Distance is the nearest approach (in meters) of the recorded track
+	Distance float64
+}
+
+// Adherence is the result of comparing a recorded track to a course
+type Adherence struct {
+	// CourseLength is the 2D length of the course in meters
+	CourseLength float64
+	// Coverage is the percentage of the course length with a recorded track
+	// within AdherenceOptions.MaxDeviation
+	Coverage          float64
+	OffCourse         []OffCourse
+	MissedCheckpoints []MissedCheckpoint
+}
+
+// polylineChunkLegs is the number of legs in a polylineIndex chunk
+const polylineChunkLegs = 16
+
+// polylineChunk is a run of consecutive legs (from the point from to the
+// point to) of a polyline with their bounds
+type polylineChunk struct {
+	polylineNo, from, to int
+	bounds               GpxBounds
+	// slack (in meters) for the difference between the bounds and legs
+	// (great circles) distances
+	slack float64
+}
+
+// polylineIndex finds the nearest location on a set of polylines. The legs
+// are grouped in chunks and only chunks with bounds nearer than the nearest
+// leg found so far are checked.
+type polylineIndex struct {
+	polylines [][]GPXPoint
+	distances [][]float64
+	chunks    []polylineChunk
+}
+
+func newPolylineIndex(polylines [][]GPXPoint) *polylineIndex {
+	pi := &polylineIndex{
+		polylines: polylines,
+		distances: make([][]float64, len(polylines)),
+		chunks:    make([]polylineChunk, 0),
+	}
+	for polylineNo, points := range polylines {
+		pi.distances[polylineNo] = cumulativeDistances(points)
+		for from := 0; from < len(points); from += polylineChunkLegs {
+			to := from + polylineChunkLegs
+			if to >= len(points) {
+				to = len(points) - 1
+			}
+			chunk := polylineChunk{polylineNo: polylineNo, from: from, to: to, bounds: GpxBounds{
+				MinLatitude:  math.MaxFloat64,
+				MaxLatitude:  -math.MaxFloat64,
+				MinLongitude: math.MaxFloat64,
+				MaxLongitude: -math.MaxFloat64,
+			}}
+			for pointNo := from; pointNo <= to; pointNo++ {
+				chunk.bounds.MinLatitude = math.Min(chunk.bounds.MinLatitude, points[pointNo].Latitude)
+				chunk.bounds.MaxLatitude = math.Max(chunk.bounds.MaxLatitude, points[pointNo].Latitude)
+				chunk.bounds.MinLongitude = math.Min(chunk.bounds.MinLongitude, points[pointNo].Longitude)
+				chunk.bounds.MaxLongitude = math.Max(chunk.bounds.MaxLongitude, points[pointNo].Longitude)
+				if pointNo > from {
+					leg := pi.distances[polylineNo][pointNo] - pi.distances[polylineNo][pointNo-1]
+					chunk.slack = math.Max(chunk.slack, leg*leg/(4*earthRadius))
+				}
+			}
+			chunk.slack++
+			pi.chunks = append(pi.chunks, chunk)
+			if to == len(points)-1 {
+				break
+			}
+		}
+	}
+	return pi
+}
+
+// nearest returns the distance of the point from the nearest polyline and
+// the distance along it (see cumulativeDistances) of the nearest location
+// (+Inf if there are no points)
+func (pi *polylineIndex) nearest(point Point) (distance float64, polylineNo int, along float64) {
+	distance = math.Inf(1)
+	minDistances := make([]float64, len(pi.chunks))
+	order := make([]int, len(pi.chunks))
+	for chunkNo, chunk := range pi.chunks {
+		nearest := Point{
+			Latitude:  math.Max(chunk.bounds.MinLatitude, math.Min(chunk.bounds.MaxLatitude, point.Latitude)),
+			Longitude: math.Max(chunk.bounds.MinLongitude, math.Min(chunk.bounds.MaxLongitude, point.Longitude)),
+		}
+		minDistances[chunkNo] = nearest.Distance2D(&point) - chunk.slack
+		order[chunkNo] = chunkNo
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return minDistances[order[i]] < minDistances[order[j]]
+	})
+
+	legNo := -1
+	for _, chunkNo := range order {
+		if minDistances[chunkNo] > distance {
+			break
+		}
+		chunk := pi.chunks[chunkNo]
+		points := pi.polylines[chunk.polylineNo]
+		for pointNo := chunk.from; pointNo < chunk.to || pointNo == chunk.from; pointNo++ {
+			// A polyline with only one point is a leg from the point to itself:
+			end := pointNo + 1
+			if end > chunk.to {
+				end = chunk.to
+			}
+			d, a := distanceFromSegment(point, points[pointNo], points[end])
+			// The first of equally near legs (like nearestOnPolyline):
+			if d < distance || (d == distance && (chunk.polylineNo < polylineNo || (chunk.polylineNo == polylineNo && pointNo < legNo))) {
+				distance, polylineNo, legNo, along = d, chunk.polylineNo, pointNo, pi.distances[chunk.polylineNo][pointNo]+a
+			}
+		}
+	}
+	return distance, polylineNo, along
+}
+
+func courseAdherence(course []GPXPoint, recorded *GPX, checkpoints []GPXPoint, opts AdherenceOptions) Adherence {
+	opts = opts.withDefaults()
+	result := Adherence{
+		OffCourse:         make([]OffCourse, 0),
+		MissedCheckpoints: make([]MissedCheckpoint, 0),
+	}
+	if len(course) == 0 {
+		return result
+	}
+	courseDistances := cumulativeDistances(course)
+	result.CourseLength = courseDistances[len(courseDistances)-1]
+	courseIndex := newPolylineIndex([][]GPXPoint{course})
+
+	// Off course stretches (they never continue across segments):
+	for trackNo, track := range recorded.Tracks {
+		for segmentNo, segment := range track.Segments {
+			var current *OffCourse
+			for pointNo := range segment.Points {
+				deviation, _, _ := courseIndex.nearest(segment.Points[pointNo].Point)
+				if deviation <= opts.MaxDeviation {
+					current = nil
+					continue
+				}
+				position := TrackPosition{Point: segment.Points[pointNo].Point, TrackNo: trackNo, SegmentNo: segmentNo, PointNo: pointNo}
+				if current == nil {
+					result.OffCourse = append(result.OffCourse, OffCourse{Start: position})
+					current = &result.OffCourse[len(result.OffCourse)-1]
+				}
+				current.End = position
+				current.MaxDeviation = math.Max(current.MaxDeviation, deviation)
+			}
+		}
+	}
+
+	// Coverage, the course is sampled and every sample must be near the
+	// recorded track:
+	recordedSegments := recorded.allSegments()
+	recordedPolylines := make([][]GPXPoint, len(recordedSegments))
+	for segmentNo := range recordedSegments {
+		recordedPolylines[segmentNo] = recordedSegments[segmentNo].Points
+	}
+	recordedIndex := newPolylineIndex(recordedPolylines)
+	nearRecorded := func(point Point) float64 {
+		distance, _, _ := recordedIndex.nearest(point)
+		return distance
+	}
+	if result.CourseLength == 0 {
+		if nearRecorded(course[0].Point) <= opts.MaxDeviation {
+			result.Coverage = 100
+		}
+	} else {
+		samples := int(math.Ceil(result.CourseLength / opts.SampleDistance))
+		var covered int
+		for sampleNo := 0; sampleNo < samples; sampleNo++ {
+			// The middle of every sample interval:
+			distance := (float64(sampleNo) + 0.5) * result.CourseLength / float64(samples)
+			if nearRecorded(locationAtDistance(course, courseDistances, distance)) <= opts.MaxDeviation {
+				covered++
+			}
+		}
+		result.Coverage = 100 * float64(covered) / float64(samples)
+	}
+
+	// Missed checkpoints are positioned on the course like waypoints (see
+	// GPX.GetLocationsPositionsOnTrack), or at the nearest course location
+	// if they are far from the course:
+	missed := make([]Location, 0)
+	for checkpointNo := range checkpoints {
+		if distance := nearRecorded(checkpoints[checkpointNo].Point); distance > opts.CheckpointRadius {
+			result.MissedCheckpoints = append(result.MissedCheckpoints, MissedCheckpoint{Checkpoint: checkpoints[checkpointNo], Distance: distance})
+			missed = append(missed, &checkpoints[checkpointNo])
+		}
+	}
+	if len(missed) > 0 {
+		courseGPX := GPX{Tracks: []GPXTrack{{Segments: []GPXTrackSegment{{Points: course}}}}}
+		positions := courseGPX.GetLocationsPositionsOnTrack(len(course), missed...)
+		for missedNo := range result.MissedCheckpoints {
+			if len(positions[missedNo]) > 0 {
+				result.MissedCheckpoints[missedNo].CourseDistance = positions[missedNo][0]
+			} else {
+				_, _, result.MissedCheckpoints[missedNo].CourseDistance = courseIndex.nearest(result.MissedCheckpoints[missedNo].Checkpoint.Point)
+			}
+		}
+	}
+
+	return result
+}
+
+// Adherence compares the recorded tracks to the route. Checkpoints (for
+// example the course waypoints) are missed if no recorded track comes
+// within opts.CheckpointRadius.
+func (rte *GPXRoute) Adherence(recorded *GPX, checkpoints []GPXPoint, opts AdherenceOptions) Adherence {
+	return courseAdherence(rte.Points, recorded, checkpoints, opts)
+}
+
+// Adherence compares the recorded tracks to this track (segments are
+// joined). Checkpoints (for example the course waypoints) are missed if no
+// recorded track comes within opts.CheckpointRadius.
+func (trk *GPXTrack) Adherence(recorded *GPX, checkpoints []GPXPoint, opts AdherenceOptions) Adherence {
+	points := make([]GPXPoint, 0, trk.GetTrackPointsNo())
+	for _, segment := range trk.Segments {
+		points = append(points, segment.Points...)
+	}
+	return courseAdherence(points, recorded, checkpoints, opts)
+}
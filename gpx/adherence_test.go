@@ -0,0 +1,121 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRecorded(points ...[]GPXPoint) *GPX {
+	trk := GPXTrack{}
+	for _, segmentPoints := range points {
+		trk.Segments = append(trk.Segments, GPXTrackSegment{Points: segmentPoints})
+	}
+	return &GPX{Tracks: []GPXTrack{trk}}
+}
+
+func TestDistanceFromSegment(t *testing.T) {
+	t.Parallel()
+
+	points := newTestZigZagPoints(0, 0)
+	beside := newTestZigZagPoints(0, 20)[1].Point
+	distance, along := distanceFromSegment(beside, points[0], points[1])
+	assert.InDelta(t, 20, distance, 0.5)
+	assert.InDelta(t, 100, along, 0.5)
+
+	behind := newTestZigZagPoints(0, 0, 0)[2].Point
+	distance, along = distanceFromSegment(behind, points[0], points[1])
+	assert.InDelta(t, 100, distance, 0.5)
+	assert.InDelta(t, 100, along, 0.5)
+}
+
+func TestPolylineIndexNearest(t *testing.T) {
+	t.Parallel()
+
+	offsets := make([]float64, 40)
+	for n := range offsets {
+		offsets[n] = float64(n%7) * 30
+	}
+	course := newTestZigZagPoints(offsets...)
+	single := newTestZigZagPoints(2000)[:1]
+	index := newPolylineIndex([][]GPXPoint{course, single})
+	distances := cumulativeDistances(course)
+	for _, offset := range []float64{0, 15, 100, 800, 3000} {
+		queries := make([]float64, 45)
+		for n := range queries {
+			queries[n] = offset
+		}
+		for _, point := range newTestZigZagPoints(queries...) {
+			expectedDistance, expectedAlong := nearestOnPolyline(course, distances, point.Point)
+			expectedPolylineNo := 0
+			if d := single[0].Distance2D(&point); d < expectedDistance {
+				expectedDistance, expectedPolylineNo, expectedAlong = d, 1, 0
+			}
+			distance, polylineNo, along := index.nearest(point.Point)
+			assert.InDelta(t, expectedDistance, distance, 0.001)
+			assert.Equal(t, expectedPolylineNo, polylineNo)
+			assert.InDelta(t, expectedAlong, along, 0.001)
+		}
+	}
+
+	distance, _, _ := newPolylineIndex([][]GPXPoint{}).nearest(course[0].Point)
+	assert.True(t, math.IsInf(distance, 1))
+}
+
+func TestAdherenceFullCourse(t *testing.T) {
+	t.Parallel()
+
+	course := GPXRoute{Points: newTestZigZagPoints(0, 0, 0, 0, 0)}
+	recorded := newTestRecorded(newTestZigZagPoints(10, -10, 10, -10, 10))
+
+	adherence := course.Adherence(recorded, course.Points, AdherenceOptions{})
+	assert.InDelta(t, 400, adherence.CourseLength, 1)
+	assert.Equal(t, 100.0, adherence.Coverage)
+	assert.Equal(t, 0, len(adherence.OffCourse))
+	assert.Equal(t, 0, len(adherence.MissedCheckpoints))
+}
+
+func TestAdherenceOffCourse(t *testing.T) {
+	t.Parallel()
+
+	course := GPXTrack{Segments: []GPXTrackSegment{{Points: newTestZigZagPoints(0, 0, 0, 0, 0)}}}
+	recorded := newTestRecorded(newTestZigZagPoints(0, 5, 100, 120, 5), newTestZigZagPoints(60, 0))
+
+	adherence := course.Adherence(recorded, nil, AdherenceOptions{})
+	assert.Equal(t, 2, len(adherence.OffCourse))
+	assert.Equal(t, 2, adherence.OffCourse[0].Start.PointNo)
+	assert.Equal(t, 3, adherence.OffCourse[0].End.PointNo)
+	assert.InDelta(t, 120, adherence.OffCourse[0].MaxDeviation, 1)
+	assert.Equal(t, 1, adherence.OffCourse[1].Start.SegmentNo)
+	assert.Equal(t, 0, adherence.OffCourse[1].Start.PointNo)
+	assert.Equal(t, 0, adherence.OffCourse[1].End.PointNo)
+}
+
+func TestAdherencePartialCourse(t *testing.T) {
+	t.Parallel()
+
+	course := GPXRoute{Points: newTestZigZagPoints(0, 0, 0, 0, 0)}
+	recorded := newTestRecorded(newTestZigZagPoints(0, 0, 0))
+
+	checkpoints := []GPXPoint{course.Points[1], course.Points[4]}
+	checkpoints[1].Name = "Finish"
+
+	adherence := course.Adherence(recorded, checkpoints, AdherenceOptions{})
+	// Covered up to 200m plus the 50m max deviation:
+	assert.InDelta(t, 62.5, adherence.Coverage, 1)
+	assert.Equal(t, 0, len(adherence.OffCourse))
+	assert.Equal(t, 1, len(adherence.MissedCheckpoints))
+	assert.Equal(t, "Finish", adherence.MissedCheckpoints[0].Checkpoint.Name)
+	assert.InDelta(t, 400, adherence.MissedCheckpoints[0].CourseDistance, 1)
+	assert.InDelta(t, 200, adherence.MissedCheckpoints[0].Distance, 1)
+
+	adherence = course.Adherence(&GPX{}, checkpoints, AdherenceOptions{})
+	assert.Equal(t, 0.0, adherence.Coverage)
+	assert.Equal(t, 2, len(adherence.MissedCheckpoints))
+}
@@ -0,0 +1,241 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"time"
+)
+
+// BestEffort is the best window (for example the fastest kilometer) found in a GPX
+type BestEffort struct {
+	// Value is seconds for FastestDistance, m/s for BestAverageSpeed and meters for BestClimb
+	Value float64
+	// Start and End are interpolated window edges. PointNo is the index of the track point before (or at) the edge.
+	Start TrackPosition
+	End   TrackPosition
+	// StartTime and EndTime are interpolated window times (zero if the track has no times)
+	StartTime time.Time
+	EndTime   time.Time
+	// StartDistance and EndDistance are 2D distances from the GPX start in meters
+	StartDistance float64
+	EndDistance   float64
+}
+
+// Duration returns the best effort window duration in seconds
+func (be BestEffort) Duration() float64 {
+	if be.StartTime.IsZero() || be.EndTime.IsZero() {
+		return 0
+	}
+	return be.EndTime.Sub(be.StartTime).Seconds()
+}
+
+// Distance returns the best effort window 2D length in meters
+func (be BestEffort) Distance() float64 {
+	return be.EndDistance - be.StartDistance
+}
+
+type effortPoint struct {
+	trackNo   int
+	segmentNo int
+	pointNo   int
+	point     *GPXPoint
+	distance  float64
+	seconds   float64
+}
+
+// effortPoints flattens all track points. Distances from start don't count
+// gaps between segments (but times do). If withTime is true, points without
+// timestamps (or with a timestamp before the previous one) are ignored. If
+// withElevation is true, points without elevation are ignored.
+func (g *GPX) effortPoints(withTime, withElevation bool) []effortPoint {
+	result := make([]effortPoint, 0, g.GetTrackPointsNo())
+	var start time.Time
+	var fromStart float64
+	for trackNo := range g.Tracks {
+		for segmentNo := range g.Tracks[trackNo].Segments {
+			seg := &g.Tracks[trackNo].Segments[segmentNo]
+			for pointNo := range seg.Points {
+				point := &seg.Points[pointNo]
+				if pointNo > 0 {
+					fromStart += point.Distance2D(&seg.Points[pointNo-1])
+				}
+				if withElevation && point.Elevation.Null() {
+					continue
+				}
+				var seconds float64
+				if withTime {
+					if !hasTimestamp(point) {
+						continue
+					}
+					if len(result) == 0 {
+						start = point.Timestamp
+					}
+					seconds = point.Timestamp.Sub(start).Seconds()
+					if len(result) > 0 && seconds < result[len(result)-1].seconds {
+						continue
+					}
+				}
+				result = append(result, effortPoint{trackNo: trackNo, segmentNo: segmentNo, pointNo: pointNo, point: point, distance: fromStart, seconds: seconds})
+			}
+		}
+	}
+	return result
+}
+
+// windowEdge is a location between the flattened points index and index+1
+type windowEdge struct {
+	index int
+	ratio float64
+}
+
+// bestWindow finds the window of the given width (in xs units) with the
+// best (as defined by better) difference of the ys values at its edges.
+// Values between points are linearly interpolated. The xs must be sorted.
+//
+// Since the interpolated values are piecewise linear, the best window
+// always has at least one edge at an existing point. The algorithm checks
+// all windows starting and all windows ending at a point with two moving
+// pointers, so it is O(n).
+func bestWindow(xs, ys []float64, width float64, better func(candidate, best float64) bool) (start, end windowEdge, value float64, found bool) {
+	n := len(xs)
+	if n < 2 || width <= 0 {
+		return
+	}
+
+	interpolate := func(k int, x float64) (float64, float64) {
+		dx := xs[k+1] - xs[k]
+		if dx <= 0 {
+			return ys[k+1], 1
+		}
+		ratio := (x - xs[k]) / dx
+		return ys[k] + (ys[k+1]-ys[k])*ratio, ratio
+	}
+	check := func(candidateStart, candidateEnd windowEdge, candidate float64) {
+		candidateStart, candidateEnd = normalizeEdge(candidateStart, n), normalizeEdge(candidateEnd, n)
+		if !found || better(candidate, value) {
+			start, end, value, found = candidateStart, candidateEnd, candidate, true
+		}
+	}
+
+	// Windows starting at points:
+	j := 0
+	for i := 0; i < n; i++ {
+		target := xs[i] + width
+		for j < n && xs[j] < target {
+			j++
+		}
+		if j == n {
+			break
+		}
+		if xs[j] == target {
+			check(windowEdge{index: i}, windowEdge{index: j}, ys[j]-ys[i])
+		} else {
+			y, ratio := interpolate(j-1, target)
+			check(windowEdge{index: i}, windowEdge{index: j - 1, ratio: ratio}, y-ys[i])
+		}
+	}
+
+	// Windows ending at points:
+	i := 0
+	for j := 0; j < n; j++ {
+		target := xs[j] - width
+		if target < xs[0] {
+			continue
+		}
+		for i+1 < n && xs[i+1] <= target {
+			i++
+		}
+		if xs[i] == target || i+1 >= n {
+			check(windowEdge{index: i}, windowEdge{index: j}, ys[j]-ys[i])
+		} else {
+			y, ratio := interpolate(i, target)
+			check(windowEdge{index: i, ratio: ratio}, windowEdge{index: j}, ys[j]-y)
+		}
+	}
+
+	return
+}
+
+// normalizeEdge moves edges (almost) at the next point to that point
+func normalizeEdge(edge windowEdge, n int) windowEdge {
+	if edge.ratio > 1-1e-6 && edge.index+1 < n {
+		return windowEdge{index: edge.index + 1}
+	}
+	return edge
+}
+
+func effortPosition(points []effortPoint, edge windowEdge) (TrackPosition, GPXPoint, float64) {
+	current := points[edge.index]
+	point := *current.point
+	distance := current.distance
+	if edge.ratio > 0 && edge.index+1 < len(points) {
+		next := points[edge.index+1]
+		if next.trackNo == current.trackNo && next.segmentNo == current.segmentNo {
+			point = interpolatePoints(current.point, next.point, edge.ratio)
+		}
+		distance = interpolateFloat64(current.distance, next.distance, edge.ratio)
+	}
+	position := TrackPosition{Point: point.Point, TrackNo: current.trackNo, SegmentNo: current.segmentNo, PointNo: current.pointNo}
+	return position, point, distance
+}
+
+func newBestEffort(points []effortPoint, start, end windowEdge, value float64) BestEffort {
+	var result BestEffort
+	var startPoint, endPoint GPXPoint
+	result.Value = value
+	result.Start, startPoint, result.StartDistance = effortPosition(points, start)
+	result.End, endPoint, result.EndDistance = effortPosition(points, end)
+	if hasTimestamp(&startPoint) && hasTimestamp(&endPoint) {
+		result.StartTime, result.EndTime = startPoint.Timestamp, endPoint.Timestamp
+	}
+	return result
+}
+
+// FastestDistance returns the fastest window of the given 2D distance (in
+// meters) over all tracks. The value is the duration in seconds.
+func (g *GPX) FastestDistance(meters float64) (BestEffort, bool) {
+	points := g.effortPoints(true, false)
+	xs, ys := make([]float64, len(points)), make([]float64, len(points))
+	for pointNo, point := range points {
+		xs[pointNo], ys[pointNo] = point.distance, point.seconds
+	}
+	start, end, value, found := bestWindow(xs, ys, meters, func(candidate, best float64) bool { return candidate < best })
+	if !found {
+		return BestEffort{}, false
+	}
+	return newBestEffort(points, start, end, value), true
+}
+
+// BestAverageSpeed returns the window of the given duration with the best
+// average speed over all tracks. The value is the speed in m/s.
+func (g *GPX) BestAverageSpeed(d time.Duration) (BestEffort, bool) {
+	points := g.effortPoints(true, false)
+	xs, ys := make([]float64, len(points)), make([]float64, len(points))
+	for pointNo, point := range points {
+		xs[pointNo], ys[pointNo] = point.seconds, point.distance
+	}
+	start, end, value, found := bestWindow(xs, ys, d.Seconds(), func(candidate, best float64) bool { return candidate > best })
+	if !found {
+		return BestEffort{}, false
+	}
+	return newBestEffort(points, start, end, value/d.Seconds()), true
+}
+
+// BestClimb returns the window of the given 2D distance (in meters) with the
+// biggest elevation difference between its end and start. The value is the
+// elevation difference in meters.
+func (g *GPX) BestClimb(meters float64) (BestEffort, bool) {
+	points := g.effortPoints(false, true)
+	xs, ys := make([]float64, len(points)), make([]float64, len(points))
+	for pointNo, point := range points {
+		xs[pointNo], ys[pointNo] = point.distance, point.point.Elevation.Value()
+	}
+	start, end, value, found := bestWindow(xs, ys, meters, func(candidate, best float64) bool { return candidate > best })
+	if !found {
+		return BestEffort{}, false
+	}
+	return newBestEffort(points, start, end, value), true
+}
@@ -0,0 +1,114 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBestWindow(t *testing.T) {
+	t.Parallel()
+
+	xs := []float64{0, 10, 20, 30, 40}
+	ys := []float64{0, 10, 12, 30, 31}
+	start, end, value, found := bestWindow(xs, ys, 15, func(candidate, best float64) bool { return candidate > best })
+	assert.True(t, found)
+	// From 15 (y=11) to 30 (y=30):
+	assert.InDelta(t, 19, value, 0.0001)
+	assert.Equal(t, windowEdge{index: 1, ratio: 0.5}, start)
+	assert.Equal(t, windowEdge{index: 3}, end)
+
+	_, _, _, found = bestWindow(xs, ys, 50, func(candidate, best float64) bool { return candidate > best })
+	assert.False(t, found)
+}
+
+func TestFastestDistance(t *testing.T) {
+	t.Parallel()
+
+	// 100m every 20s, but 100m every 10s between points 3 and 5:
+	seg := newTestSegment(20, 100, 100, 100, 100, 100, 100)
+	for pointNo, seconds := range []int{0, 20, 40, 60, 70, 80, 100} {
+		seg.Points[pointNo].Timestamp = testStartTime.Add(time.Duration(seconds) * time.Second)
+	}
+	g := GPX{}
+	g.AppendSegment(&seg)
+
+	effort, found := g.FastestDistance(200)
+	assert.True(t, found)
+	assert.InDelta(t, 20, effort.Value, 0.0001)
+	assert.Equal(t, 3, effort.Start.PointNo)
+	assert.Equal(t, 5, effort.End.PointNo)
+	assert.InDelta(t, 300, effort.StartDistance, 0.01)
+	assert.InDelta(t, 200, effort.Distance(), 0.01)
+	assert.InDelta(t, 20, effort.Duration(), 0.0001)
+
+	effort, found = g.FastestDistance(150)
+	assert.True(t, found)
+	assert.InDelta(t, 15, effort.Value, 0.0001)
+	assert.InDelta(t, 150, effort.Distance(), 0.01)
+
+	_, found = g.FastestDistance(10000)
+	assert.False(t, found)
+}
+
+func TestBestAverageSpeedAcrossSegments(t *testing.T) {
+	t.Parallel()
+
+	seg1 := newTestSegment(10, 50, 50)
+	seg2 := newTestSegment(10, 100, 100)
+	for pointNo := range seg2.Points {
+		seg2.Points[pointNo].Timestamp = seg2.Points[pointNo].Timestamp.Add(30 * time.Second)
+	}
+	g := GPX{}
+	g.AppendTrack(&GPXTrack{})
+	g.AppendSegment(&seg1)
+	g.AppendTrack(&GPXTrack{})
+	g.AppendSegment(&seg2)
+
+	effort, found := g.BestAverageSpeed(15 * time.Second)
+	assert.True(t, found)
+	assert.InDelta(t, 10, effort.Value, 0.0001)
+	assert.Equal(t, 1, effort.Start.TrackNo)
+	assert.Equal(t, 1, effort.End.TrackNo)
+}
+
+func TestBestClimb(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 100, 100, 100)
+	for pointNo, elevation := range []float64{100, 90, 150, 160, 100} {
+		seg.Points[pointNo].Elevation.SetValue(elevation)
+	}
+	g := GPX{}
+	g.AppendSegment(&seg)
+
+	effort, found := g.BestClimb(200)
+	assert.True(t, found)
+	assert.InDelta(t, 70, effort.Value, 0.0001)
+	assert.Equal(t, 1, effort.Start.PointNo)
+	assert.Equal(t, 3, effort.End.PointNo)
+}
+
+func TestFastestDistanceOnLongTrack(t *testing.T) {
+	t.Parallel()
+
+	distances := make([]float64, 100000)
+	for i := range distances {
+		distances[i] = 10
+	}
+	seg := newTestSegment(3, distances...)
+	g := GPX{}
+	g.AppendSegment(&seg)
+
+	started := time.Now()
+	effort, found := g.FastestDistance(5000)
+	assert.True(t, found)
+	assert.InDelta(t, 1500, effort.Value, 0.01)
+	assert.True(t, time.Since(started) < 5*time.Second)
+}
@@ -0,0 +1,261 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"fmt"
+	"math"
+)
+
+// ClimbCategory is a Tour de France style climb category
+type ClimbCategory int
+
+const (
+	ClimbUncategorized ClimbCategory = iota
+	ClimbCategory4
+	ClimbCategory3
+	ClimbCategory2
+	ClimbCategory1
+	ClimbHorsCategorie
+)
+
+func (c ClimbCategory) String() string {
+	switch c {
+	case ClimbCategory4:
+		return "4"
+	case ClimbCategory3:
+		return "3"
+	case ClimbCategory2:
+		return "2"
+	case ClimbCategory1:
+		return "1"
+	case ClimbHorsCategorie:
+		return "HC"
+	}
+	return ""
+}
+
+// climbCategory uses the length (in meters) times average gradient (in
+// percent) score: 8000 for category 4, 16000 for 3, 32000 for 2, 64000 for 1
+// and 80000 for HC.
+func climbCategory(score float64) ClimbCategory {
+	switch {
+	case score >= 80000:
+		return ClimbHorsCategorie
+	case score >= 64000:
+		return ClimbCategory1
+	case score >= 32000:
+		return ClimbCategory2
+	case score >= 16000:
+		return ClimbCategory3
+	case score >= 8000:
+		return ClimbCategory4
+	}
+	return ClimbUncategorized
+}
+
+// ClimbOptions contains the climb (and descent) detection settings. Zero
+// fields are replaced with the values from DefaultClimbOptions.
+type ClimbOptions struct {
+	// MinElevationGain is the minimal elevation difference in meters
+	MinElevationGain float64
+	// MinAverageGradient is the minimal average gradient in percent
+	MinAverageGradient float64
+	// MaxDip is the elevation loss (gain for descents) in meters after which a climb ends
+	MaxDip float64
+	// GradientDistance is the distance (in meters) used to compute the maximum gradient
+	GradientDistance float64
+}
+
+// DefaultClimbOptions are used for zero ClimbOptions fields
+var DefaultClimbOptions = ClimbOptions{
+	MinElevationGain:   20,
+	MinAverageGradient: 3,
+	MaxDip:             10,
+	GradientDistance:   100,
+}
+
+func (opts ClimbOptions) withDefaults() ClimbOptions {
+	if opts.MinElevationGain <= 0 {
+		opts.MinElevationGain = DefaultClimbOptions.MinElevationGain
+	}
+	if opts.MinAverageGradient <= 0 {
+		opts.MinAverageGradient = DefaultClimbOptions.MinAverageGradient
+	}
+	if opts.MaxDip <= 0 {
+		opts.MaxDip = DefaultClimbOptions.MaxDip
+	}
+	if opts.GradientDistance <= 0 {
+		opts.GradientDistance = DefaultClimbOptions.GradientDistance
+	}
+	return opts
+}
+
+// Climb is a climb or a descent on a track
+type Climb struct {
+	Descent bool
+	Start   TrackPosition
+	End     TrackPosition
+	// StartDistance is the 2D distance (in meters) from the track start
+	StartDistance float64
+	// Length is the 2D length in meters
+	Length float64
+	// ElevationDifference is end minus start elevation in meters (negative for descents)
+	ElevationDifference float64
+	// AverageGradient in percent (negative for descents)
+	AverageGradient float64
+	// MaxGradient is the steepest gradient (in percent) over ClimbOptions.GradientDistance (the minimum for descents)
+	MaxGradient float64
+	// Category is always ClimbUncategorized for descents
+	Category ClimbCategory
+}
+
+// Score returns the length (in meters) times average gradient (in percent)
+func (c Climb) Score() float64 {
+	return c.Length * math.Abs(c.AverageGradient)
+}
+
+func (c Climb) String() string {
+	kind := "Climb"
+	if c.Descent {
+		kind = "Descent"
+	} else if c.Category != ClimbUncategorized {
+		kind = fmt.Sprintf("Category %s climb", c.Category)
+	}
+	return fmt.Sprintf("%s at km %.1f: %.1fkm at %.1f%% (max %.1f%%), %+.0fm", kind, c.StartDistance/1000, c.Length/1000, c.AverageGradient, c.MaxGradient, c.ElevationDifference)
+}
+
+// Climbs returns the climbs in the segment
+func (seg *GPXTrackSegment) Climbs(opts ClimbOptions) []Climb {
+	return seg.climbs(opts, false)
+}
+
+// Descents returns the descents in the segment
+func (seg *GPXTrackSegment) Descents(opts ClimbOptions) []Climb {
+	return seg.climbs(opts, true)
+}
+
+func (seg *GPXTrackSegment) climbs(opts ClimbOptions, descents bool) []Climb {
+	opts = opts.withDefaults()
+
+	elevations := seg.Elevations()
+	pointNos := make([]int, 0, len(elevations))
+	xs := make([]float64, 0, len(elevations))
+	ys := make([]float64, 0, len(elevations))
+	var fromStart float64
+	for pointNo := range seg.Points {
+		if pointNo > 0 {
+			fromStart += seg.Points[pointNo].Distance2D(&seg.Points[pointNo-1])
+		}
+		if elevations[pointNo].Null() {
+			continue
+		}
+		elevation := elevations[pointNo].Value()
+		if descents {
+			elevation = -elevation
+		}
+		pointNos = append(pointNos, pointNo)
+		xs = append(xs, fromStart)
+		ys = append(ys, elevation)
+	}
+
+	result := make([]Climb, 0)
+	appendClimb := func(start, top int) {
+		length := xs[top] - xs[start]
+		gain := ys[top] - ys[start]
+		if length <= 0 || gain < opts.MinElevationGain || gain/length*100 < opts.MinAverageGradient {
+			return
+		}
+		climb := Climb{
+			Descent:             descents,
+			Start:               TrackPosition{Point: seg.Points[pointNos[start]].Point, PointNo: pointNos[start]},
+			End:                 TrackPosition{Point: seg.Points[pointNos[top]].Point, PointNo: pointNos[top]},
+			StartDistance:       xs[start],
+			Length:              length,
+			ElevationDifference: gain,
+			AverageGradient:     gain / length * 100,
+		}
+		climb.MaxGradient = climb.AverageGradient
+		if _, _, steepest, found := bestWindow(xs[start:top+1], ys[start:top+1], opts.GradientDistance, func(candidate, best float64) bool { return candidate > best }); found {
+			climb.MaxGradient = math.Max(climb.MaxGradient, steepest/opts.GradientDistance*100)
+		}
+		if descents {
+			climb.ElevationDifference, climb.AverageGradient, climb.MaxGradient = -climb.ElevationDifference, -climb.AverageGradient, -climb.MaxGradient
+		} else {
+			climb.Category = climbCategory(climb.Score())
+		}
+		result = append(result, climb)
+	}
+
+	// The climb starts at the lowest point before the top and ends at the
+	// top, when the elevation drops more than MaxDip below it, below the
+	// climb start (or at the segment end).
+	start, top := 0, 0
+	for i := range ys {
+		if ys[i] <= ys[start] {
+			if top > start {
+				appendClimb(start, top)
+			}
+			start, top = i, i
+		} else if ys[i] > ys[top] {
+			top = i
+		} else if ys[top]-ys[i] > opts.MaxDip {
+			appendClimb(start, top)
+			start, top = i, i
+		}
+	}
+	if top > start {
+		appendClimb(start, top)
+	}
+
+	return result
+}
+
+func (trk *GPXTrack) climbs(opts ClimbOptions, descents bool) []Climb {
+	result := make([]Climb, 0)
+	var fromStart float64
+	for segmentNo := range trk.Segments {
+		seg := &trk.Segments[segmentNo]
+		for _, climb := range seg.climbs(opts, descents) {
+			climb.Start.SegmentNo, climb.End.SegmentNo = segmentNo, segmentNo
+			climb.StartDistance += fromStart
+			result = append(result, climb)
+		}
+		fromStart += seg.Length2D()
+	}
+	return result
+}
+
+// Climbs returns the climbs in all track segments
+func (trk *GPXTrack) Climbs(opts ClimbOptions) []Climb {
+	return trk.climbs(opts, false)
+}
+
+// Descents returns the descents in all track segments
+func (trk *GPXTrack) Descents(opts ClimbOptions) []Climb {
+	return trk.climbs(opts, true)
+}
+
+func (g *GPX) climbs(opts ClimbOptions, descents bool) []Climb {
+	result := make([]Climb, 0)
+	for trackNo := range g.Tracks {
+		for _, climb := range g.Tracks[trackNo].climbs(opts, descents) {
+			climb.Start.TrackNo, climb.End.TrackNo = trackNo, trackNo
+			result = append(result, climb)
+		}
+	}
+	return result
+}
+
+// Climbs returns the climbs in all tracks. StartDistance is relative to the start of each track.
+func (g *GPX) Climbs(opts ClimbOptions) []Climb {
+	return g.climbs(opts, false)
+}
+
+// Descents returns the descents in all tracks. StartDistance is relative to the start of each track.
+func (g *GPX) Descents(opts ClimbOptions) []Climb {
+	return g.climbs(opts, true)
+}
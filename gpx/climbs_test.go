@@ -0,0 +1,138 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestElevationSegment(step float64, elevations ...float64) GPXTrackSegment {
+	distances := make([]float64, len(elevations)-1)
+	for i := range distances {
+		distances[i] = step
+	}
+	seg := newTestSegment(10, distances...)
+	for pointNo, elevation := range elevations {
+		seg.Points[pointNo].Elevation.SetValue(elevation)
+	}
+	return seg
+}
+
+func TestClimbCategory(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, ClimbUncategorized, climbCategory(7999))
+	assert.Equal(t, ClimbCategory4, climbCategory(8000))
+	assert.Equal(t, ClimbCategory3, climbCategory(20000))
+	assert.Equal(t, ClimbCategory2, climbCategory(50000))
+	assert.Equal(t, ClimbCategory1, climbCategory(70000))
+	assert.Equal(t, ClimbHorsCategorie, climbCategory(100000))
+	assert.Equal(t, "HC", ClimbHorsCategorie.String())
+	assert.Equal(t, "", ClimbUncategorized.String())
+}
+
+func TestClimbsAndDescents(t *testing.T) {
+	t.Parallel()
+
+	// Flat, 2km climb with a small dip, flat top, 1km descent, small bump:
+	elevations := []float64{100, 100, 100}
+	for i := 1; i <= 10; i++ {
+		elevations = append(elevations, 100+float64(i)*10)
+	}
+	elevations = append(elevations, 195)
+	for i := 1; i <= 10; i++ {
+		elevations = append(elevations, 200+float64(i)*10)
+	}
+	elevations = append(elevations, 300, 300)
+	for i := 1; i <= 10; i++ {
+		elevations = append(elevations, 300-float64(i)*15)
+	}
+	elevations = append(elevations, 155, 150)
+	seg := newTestElevationSegment(100, elevations...)
+
+	climbs := seg.Climbs(ClimbOptions{})
+	if assert.Equal(t, 1, len(climbs)) {
+		climb := climbs[0]
+		assert.False(t, climb.Descent)
+		assert.Equal(t, 2, climb.Start.PointNo)
+		assert.Equal(t, 23, climb.End.PointNo)
+		assert.InDelta(t, 200, climb.StartDistance, 0.1)
+		assert.InDelta(t, 2100, climb.Length, 0.1)
+		assert.InDelta(t, 200, climb.ElevationDifference, 0.0001)
+		assert.InDelta(t, 9.52, climb.AverageGradient, 0.01)
+		assert.InDelta(t, 15, climb.MaxGradient, 0.01)
+		assert.Equal(t, ClimbCategory3, climb.Category)
+		assert.Equal(t, "Category 3 climb at km 0.2: 2.1km at 9.5% (max 15.0%), +200m", climb.String())
+	}
+
+	descents := seg.Descents(ClimbOptions{})
+	if assert.Equal(t, 1, len(descents)) {
+		descent := descents[0]
+		assert.True(t, descent.Descent)
+		assert.Equal(t, 25, descent.Start.PointNo)
+		assert.Equal(t, 35, descent.End.PointNo)
+		assert.InDelta(t, -150, descent.ElevationDifference, 0.0001)
+		assert.InDelta(t, -15, descent.AverageGradient, 0.01)
+		assert.InDelta(t, -15, descent.MaxGradient, 0.01)
+		assert.Equal(t, ClimbUncategorized, descent.Category)
+	}
+
+	// With a small dip tolerance the climb is split in two:
+	assert.Equal(t, 2, len(seg.Climbs(ClimbOptions{MaxDip: 1})))
+	assert.Equal(t, 0, len(seg.Climbs(ClimbOptions{MinElevationGain: 500})))
+}
+
+func TestClimbsInTracks(t *testing.T) {
+	t.Parallel()
+
+	seg1 := newTestElevationSegment(100, 100, 100, 100)
+	seg2 := newTestElevationSegment(100, 100, 110, 120, 130, 140)
+	g := GPX{}
+	g.AppendTrack(&GPXTrack{})
+	g.AppendTrack(&GPXTrack{})
+	g.AppendSegment(&seg1)
+	g.AppendSegment(&seg2)
+
+	climbs := g.Climbs(ClimbOptions{})
+	if assert.Equal(t, 1, len(climbs)) {
+		assert.Equal(t, 1, climbs[0].Start.TrackNo)
+		assert.Equal(t, 1, climbs[0].Start.SegmentNo)
+		assert.Equal(t, 0, climbs[0].Start.PointNo)
+		assert.InDelta(t, 200, climbs[0].StartDistance, 0.1)
+	}
+	assert.Equal(t, 0, len(g.Descents(ClimbOptions{})))
+}
+
+func TestClimbFollowedByDropBelowStart(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestElevationSegment(100, 0, 25, 50, 75, 100, -5)
+	climbs := seg.Climbs(ClimbOptions{})
+	if assert.Equal(t, 1, len(climbs)) {
+		assert.Equal(t, 0, climbs[0].Start.PointNo)
+		assert.Equal(t, 4, climbs[0].End.PointNo)
+		assert.InDelta(t, 100, climbs[0].ElevationDifference, 0.001)
+	}
+}
+
+func TestClimbWithLargeMaxDip(t *testing.T) {
+	t.Parallel()
+
+	// Gradual descent below the start, every drop is within MaxDip:
+	seg := newTestElevationSegment(100, 0, 25, 50, 75, 100, 80, 60, 40, 20, 0, -20)
+	climbs := seg.Climbs(ClimbOptions{MaxDip: 500})
+	if assert.Equal(t, 1, len(climbs)) {
+		assert.Equal(t, 0, climbs[0].Start.PointNo)
+		assert.Equal(t, 4, climbs[0].End.PointNo)
+	}
+	descents := seg.Descents(ClimbOptions{MaxDip: 500})
+	if assert.Equal(t, 1, len(descents)) {
+		assert.Equal(t, 4, descents[0].Start.PointNo)
+		assert.Equal(t, 10, descents[0].End.PointNo)
+	}
+}
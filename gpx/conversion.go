@@ -0,0 +1,125 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"fmt"
+)
+
+// DefaultRoutePointNameFormat is used to name route points without names
+const DefaultRoutePointNameFormat = "RP%03d"
+
+// ConversionOptions contains the route/track conversion settings
+type ConversionOptions struct {
+	// MaxPoints is the maximum number of points (zero for no limit)
+	MaxPoints int
+	// Tolerance in meters, points closer than this to the simplified line
+	// are removed (zero keeps all points if there is no MaxPoints limit)
+	Tolerance float64
+	// PointNameFormat is a fmt format (with the 1-based point number) for
+	// naming route points without names, DefaultRoutePointNameFormat if empty
+	PointNameFormat string
+}
+
+// simplifyToMaxPoints is a top-down Douglas-Peucker simplification which
+// always splits the interval with the most distant point first. It stops
+// when all points are within tolerance or there are maxPoints points.
+func simplifyToMaxPoints(points []GPXPoint, maxPoints int, tolerance float64) []GPXPoint {
+	if len(points) < 3 || (maxPoints <= 0 && tolerance <= 0) {
+		return append([]GPXPoint{}, points...)
+	}
+	if maxPoints > 0 && maxPoints < 2 {
+		maxPoints = 2
+	}
+
+	type interval struct {
+		first, last int
+		// most distant point and its distance from the first-last line
+		farthest int
+		distance float64
+	}
+	newInterval := func(first, last int) interval {
+		result := interval{first: first, last: last, farthest: -1, distance: -1}
+		for pointNo := first + 1; pointNo < last; pointNo++ {
+			if d := distanceFromLine(points[pointNo].Point, points[first], points[last]); d > result.distance {
+				result.farthest, result.distance = pointNo, d
+			}
+		}
+		return result
+	}
+
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	kept := 2
+	intervals := []interval{newInterval(0, len(points)-1)}
+	for maxPoints <= 0 || kept < maxPoints {
+		best := -1
+		for intervalNo := range intervals {
+			if intervals[intervalNo].farthest >= 0 && (best < 0 || intervals[intervalNo].distance > intervals[best].distance) {
+				best = intervalNo
+			}
+		}
+		if best < 0 || intervals[best].distance < tolerance {
+			break
+		}
+		split := intervals[best]
+		keep[split.farthest] = true
+		kept++
+		intervals[best] = newInterval(split.first, split.farthest)
+		intervals = append(intervals, newInterval(split.farthest, split.last))
+	}
+
+	result := make([]GPXPoint, 0, kept)
+	for pointNo := range points {
+		if keep[pointNo] {
+			result = append(result, points[pointNo])
+		}
+	}
+	return result
+}
+
+// ToTrack converts the route into a track with one segment
+func (rte *GPXRoute) ToTrack(opts ConversionOptions) GPXTrack {
+	return GPXTrack{
+		Name:        rte.Name,
+		Comment:     rte.Comment,
+		Description: rte.Description,
+		Source:      rte.Source,
+		Number:      rte.Number,
+		Type:        rte.Type,
+		Extensions:  rte.Extensions,
+		Segments:    []GPXTrackSegment{{Points: simplifyToMaxPoints(rte.Points, opts.MaxPoints, opts.Tolerance)}},
+	}
+}
+
+// ToRoute converts the track into a route (segments are joined). Route
+// points without names are named with opts.PointNameFormat.
+func (trk *GPXTrack) ToRoute(opts ConversionOptions) GPXRoute {
+	points := make([]GPXPoint, 0, trk.GetTrackPointsNo())
+	for _, seg := range trk.Segments {
+		points = append(points, seg.Points...)
+	}
+	result := GPXRoute{
+		Name:        trk.Name,
+		Comment:     trk.Comment,
+		Description: trk.Description,
+		Source:      trk.Source,
+		Number:      trk.Number,
+		Type:        trk.Type,
+		Extensions:  trk.Extensions,
+		Points:      simplifyToMaxPoints(points, opts.MaxPoints, opts.Tolerance),
+	}
+	format := opts.PointNameFormat
+	if format == "" {
+		format = DefaultRoutePointNameFormat
+	}
+	for pointNo := range result.Points {
+		if result.Points[pointNo].Name == "" {
+			result.Points[pointNo].Name = fmt.Sprintf(format, pointNo+1)
+		}
+	}
+	return result
+}
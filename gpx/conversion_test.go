@@ -0,0 +1,106 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestZigZagPoints returns points going north with a sideways offset (in meters) on every point
+func newTestZigZagPoints(offsets ...float64) []GPXPoint {
+	points := make([]GPXPoint, len(offsets))
+	for pointNo, offset := range offsets {
+		points[pointNo].Latitude = 45 + float64(pointNo)*100/oneDegree
+		points[pointNo].Longitude = 13 + offset/oneDegree/0.7071
+	}
+	return points
+}
+
+func TestSimplifyToMaxPoints(t *testing.T) {
+	t.Parallel()
+
+	points := newTestZigZagPoints(0, 1, 50, 2, 0, -20, 0, 5, 0)
+
+	assert.Equal(t, len(points), len(simplifyToMaxPoints(points, 0, 0)))
+
+	simplified := simplifyToMaxPoints(points, 3, 0)
+	if assert.Equal(t, 3, len(simplified)) {
+		assert.Equal(t, points[2], simplified[1])
+	}
+
+	simplified = simplifyToMaxPoints(points, 4, 0)
+	if assert.Equal(t, 4, len(simplified)) {
+		assert.Equal(t, points[5], simplified[2])
+	}
+
+	simplified = simplifyToMaxPoints(points, 0, 30)
+	assert.Equal(t, []GPXPoint{points[0], points[2], points[5], points[8]}, simplified)
+
+	simplified = simplifyToMaxPoints(points, 3, 30)
+	assert.Equal(t, []GPXPoint{points[0], points[2], points[8]}, simplified)
+}
+
+func TestRouteToTrack(t *testing.T) {
+	t.Parallel()
+
+	rte := GPXRoute{
+		Name:        "route",
+		Comment:     "comment",
+		Description: "description",
+		Source:      "source",
+		Number:      *NewNullableInt(7),
+		Type:        "hiking",
+		Extensions:  heartRateExtension("100"),
+		Points:      newTestZigZagPoints(0, 1, 50, 2, 0),
+	}
+	rte.Points[2].Name = "Peak"
+
+	trk := rte.ToTrack(ConversionOptions{})
+	assert.Equal(t, "route", trk.Name)
+	assert.Equal(t, "comment", trk.Comment)
+	assert.Equal(t, "description", trk.Description)
+	assert.Equal(t, "source", trk.Source)
+	assert.Equal(t, 7, trk.Number.Value())
+	assert.Equal(t, "hiking", trk.Type)
+	assert.Equal(t, rte.Extensions, trk.Extensions)
+	if assert.Equal(t, 1, len(trk.Segments)) {
+		assert.Equal(t, rte.Points, trk.Segments[0].Points)
+	}
+
+	trk = rte.ToTrack(ConversionOptions{MaxPoints: 3})
+	assert.Equal(t, 3, len(trk.Segments[0].Points))
+	assert.Equal(t, "Peak", trk.Segments[0].Points[1].Name)
+
+	// The route is not changed:
+	assert.Equal(t, 5, len(rte.Points))
+}
+
+func TestTrackToRoute(t *testing.T) {
+	t.Parallel()
+
+	points := newTestZigZagPoints(0, 1, 50, 2, 0, -20, 0, 5, 0)
+	trk := GPXTrack{Name: "track", Type: "cycling"}
+	trk.AppendSegment(&GPXTrackSegment{Points: points[:4]})
+	trk.AppendSegment(&GPXTrackSegment{Points: points[4:]})
+	trk.Segments[1].Points[1].Name = "Turn"
+
+	rte := trk.ToRoute(ConversionOptions{MaxPoints: 4})
+	assert.Equal(t, "track", rte.Name)
+	assert.Equal(t, "cycling", rte.Type)
+	if assert.Equal(t, 4, len(rte.Points)) {
+		assert.Equal(t, "RP001", rte.Points[0].Name)
+		assert.Equal(t, "RP002", rte.Points[1].Name)
+		assert.Equal(t, "Turn", rte.Points[2].Name)
+		assert.Equal(t, "RP004", rte.Points[3].Name)
+	}
+
+	rte = trk.ToRoute(ConversionOptions{PointNameFormat: "Point %d"})
+	assert.Equal(t, 9, len(rte.Points))
+	assert.Equal(t, "Point 9", rte.Points[8].Name)
+	assert.Equal(t, "", trk.Segments[0].Points[0].Name)
+}
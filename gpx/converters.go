@@ -221,7 +221,7 @@ func convertPointToGpx10(original *GPXPoint) *gpx10GpxPoint {
 	result.Lon = formattedFloat(original.Longitude)
 	result.Ele = original.Elevation
 	result.Timestamp = formatGPXTime(&original.Timestamp)
-	result.MagVar = original.MagneticVariation
+	result.MagVar = validMagneticVariation(original.MagneticVariation)
 	result.GeoIdHeight = original.GeoidHeight
 	result.Name = original.Name
 	result.Cmt = original.Comment
@@ -268,7 +268,7 @@ func convertPointFromGpx10(original *gpx10GpxPoint) *GPXPoint {
 	if time != nil {
 		result.Timestamp = *time
 	}
-	result.MagneticVariation = original.MagVar
+	result.MagneticVariation = validMagneticVariation(original.MagVar)
 	result.GeoidHeight = original.GeoIdHeight
 	result.Name = original.Name
 	result.Comment = original.Cmt
@@ -656,7 +656,7 @@ func convertPointToGpx11(original *GPXPoint) *gpx11GpxPoint {
 	result.Lon = formattedFloat(original.Longitude)
 	result.Ele = original.Elevation
 	result.Timestamp = formatGPXTime(&original.Timestamp)
-	result.MagVar = original.MagneticVariation
+	result.MagVar = validMagneticVariation(original.MagneticVariation)
 	result.GeoIdHeight = original.GeoidHeight
 	result.Name = original.Name
 	result.Cmt = original.Comment
@@ -704,7 +704,7 @@ func convertPointFromGpx11(original *gpx11GpxPoint) *GPXPoint {
 	if time != nil {
 		result.Timestamp = *time
 	}
-	result.MagneticVariation = original.MagVar
+	result.MagneticVariation = validMagneticVariation(original.MagVar)
 	result.GeoidHeight = original.GeoIdHeight
 	result.Name = original.Name
 	result.Comment = original.Cmt
@@ -0,0 +1,316 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"sort"
+)
+
+// Area is a region used for cropping
+type Area interface {
+	Contains(location Location) bool
+}
+
+// Contains returns true if the location is within the bounds (including the edges)
+func (b *GpxBounds) Contains(location Location) bool {
+	return location.GetLatitude() >= b.MinLatitude && location.GetLatitude() <= b.MaxLatitude &&
+		location.GetLongitude() >= b.MinLongitude && location.GetLongitude() <= b.MaxLongitude
+}
+
+// Polygon is an area given by an outer ring and optional holes. Rings don't
+// need to be closed (the last point is connected to the first).
+type Polygon struct {
+	Outer []Point
+	Holes [][]Point
+}
+
+// ringContains is the even-odd (ray casting) rule, with the longitude as x
+// and latitude as y
+func ringContains(ring []Point, location Location) bool {
+	x, y := location.GetLongitude(), location.GetLatitude()
+	result := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i].Longitude, ring[i].Latitude
+		xj, yj := ring[j].Longitude, ring[j].Latitude
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			result = !result
+		}
+	}
+	return result
+}
+
+// Contains returns true if the location is within the outer ring and not in a hole
+func (p *Polygon) Contains(location Location) bool {
+	if !ringContains(p.Outer, location) {
+		return false
+	}
+	for _, hole := range p.Holes {
+		if ringContains(hole, location) {
+			return false
+		}
+	}
+	return true
+}
+
+// Circle is an area within Radius meters (2D distance) from the Center
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+// Contains returns true if the location is within the circle
+func (c *Circle) Contains(location Location) bool {
+	return c.Center.Distance2D(location) <= c.Radius
+}
+
+// areaCrossing returns the (interpolated) point between inside and outside
+// where the line leaves the area, found by bisection. The result is always
+// inside (it is the inside point if the boundary is too near).
+func areaCrossing(area Area, inside, outside *GPXPoint) GPXPoint {
+	low, high := 0.0, 1.0
+	for i := 0; i < 30; i++ {
+		middle := (low + high) / 2
+		point := interpolatePoints(inside, outside, middle)
+		if area.Contains(&point) {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return interpolatePoints(inside, outside, low)
+}
+
+// areaSamples is the number of samples used to find where a line between two
+// points passes through an area (for areas other than *GpxBounds, *Polygon
+// and *Circle)
+const areaSamples = 100
+
+// ringCrossingRatios returns the ratios (see interpolatePoints) where the
+// line between two points crosses the ring edges
+func ringCrossingRatios(ring []Point, point1, point2 *GPXPoint) []float64 {
+	result := make([]float64, 0)
+	dx, dy := longitudeDelta(point1.Longitude, point2.Longitude), point2.Latitude-point1.Latitude
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		ex, ey := ring[i].Longitude-ring[j].Longitude, ring[i].Latitude-ring[j].Latitude
+		denominator := dx*ey - dy*ex
+		if denominator == 0 {
+			// Parallel
+			continue
+		}
+		wx, wy := ring[j].Longitude-point1.Longitude, ring[j].Latitude-point1.Latitude
+		ratio := (wx*ey - wy*ex) / denominator
+		edgeRatio := (wx*dy - wy*dx) / denominator
+		if ratio >= 0 && ratio <= 1 && edgeRatio >= 0 && edgeRatio <= 1 {
+			result = append(result, ratio)
+		}
+	}
+	return result
+}
+
+// circleCrossingRatios returns the ratios (see interpolatePoints) where the
+// line between two points crosses the circle (in the equirectangular
+// projection)
+func circleCrossingRatios(circle *Circle, point1, point2 *GPXPoint) []float64 {
+	result := make([]float64, 0)
+	coef := math.Cos(ToRad(circle.Center.Latitude))
+	dx, dy := longitudeDelta(point1.Longitude, point2.Longitude)*coef, point2.Latitude-point1.Latitude
+	cx, cy := longitudeDelta(point1.Longitude, circle.Center.Longitude)*coef, circle.Center.Latitude-point1.Latitude
+	radius := circle.Radius / oneDegree
+	a, b, c := dx*dx+dy*dy, cx*dx+cy*dy, cx*cx+cy*cy-radius*radius
+	if a == 0 || b*b-a*c < 0 {
+		return result
+	}
+	for _, ratio := range []float64{(b - math.Sqrt(b*b-a*c)) / a, (b + math.Sqrt(b*b-a*c)) / a} {
+		if ratio >= 0 && ratio <= 1 {
+			result = append(result, ratio)
+		}
+	}
+	return result
+}
+
+// areaPasses returns the ratios (see interpolatePoints) for every part of
+// the line between two points which is inside the area, in order. The
+// ratios are a location outside before the part (0 if the part starts at
+// point1), a location inside and a location outside after it (1 if the part
+// ends at point2).
+func areaPasses(area Area, point1, point2 *GPXPoint) [][3]float64 {
+	var boundaries []float64
+	switch a := area.(type) {
+	case *Circle:
+		boundaries = circleCrossingRatios(a, point1, point2)
+	case *GpxBounds:
+		boundaries = ringCrossingRatios([]Point{
+			{Latitude: a.MinLatitude, Longitude: a.MinLongitude},
+			{Latitude: a.MaxLatitude, Longitude: a.MinLongitude},
+			{Latitude: a.MaxLatitude, Longitude: a.MaxLongitude},
+			{Latitude: a.MinLatitude, Longitude: a.MaxLongitude},
+		}, point1, point2)
+	case *Polygon:
+		boundaries = ringCrossingRatios(a.Outer, point1, point2)
+		for _, hole := range a.Holes {
+			boundaries = append(boundaries, ringCrossingRatios(hole, point1, point2)...)
+		}
+	default:
+		boundaries = make([]float64, areaSamples+1)
+		for n := range boundaries {
+			boundaries[n] = float64(n) / areaSamples
+		}
+	}
+	boundaries = append(boundaries, 0, 1)
+	sort.Float64s(boundaries)
+
+	// The middle of every interval between the boundaries is either inside
+	// or outside:
+	result := make([][3]float64, 0)
+	outside := 0.0
+	var current *[3]float64
+	for n := 1; n < len(boundaries); n++ {
+		if boundaries[n] == boundaries[n-1] {
+			continue
+		}
+		ratio := (boundaries[n-1] + boundaries[n]) / 2
+		middle := interpolatePoints(point1, point2, ratio)
+		switch inside := area.Contains(&middle); {
+		case inside && current == nil:
+			result = append(result, [3]float64{outside, ratio, 1})
+			current = &result[len(result)-1]
+		case !inside:
+			if current != nil {
+				current[2] = ratio
+				current = nil
+			}
+			outside = ratio
+		}
+	}
+	return result
+}
+
+// cropPoints returns the parts of the line within the area, with
+// interpolated points where the line enters and leaves it. Every line
+// between two consecutive points is split where it crosses the area
+// boundary, so a line can leave and enter the area (a concave polygon or a
+// hole) even if both points are inside, or pass through it even if both
+// points are outside.
+func cropPoints(points []GPXPoint, area Area) [][]GPXPoint {
+	result := make([][]GPXPoint, 0)
+	var current []GPXPoint
+	for pointNo := range points {
+		point := &points[pointNo]
+		inside := area.Contains(point)
+		if pointNo == 0 {
+			if inside {
+				current = []GPXPoint{*point}
+			}
+			continue
+		}
+		previous := &points[pointNo-1]
+		reached := false
+		for passNo, pass := range areaPasses(area, previous, point) {
+			inner := interpolatePoints(previous, point, pass[1])
+			if current != nil && (passNo > 0 || pass[0] != 0) {
+				// The previous point is on the boundary
+				result = append(result, current)
+				current = nil
+			}
+			var enter *GPXPoint
+			if current == nil {
+				before := interpolatePoints(previous, point, pass[0])
+				crossing := areaCrossing(area, &inner, &before)
+				enter, current = &crossing, []GPXPoint{crossing}
+			}
+			if pass[2] == 1 && inside {
+				if enter != nil && enter.Point == point.Point {
+					current = current[:0]
+				}
+				current = append(current, *point)
+				reached = true
+				continue
+			}
+			after := interpolatePoints(previous, point, pass[2])
+			if leave := areaCrossing(area, &inner, &after); leave.Point != current[len(current)-1].Point {
+				current = append(current, leave)
+			}
+			result = append(result, current)
+			current = nil
+		}
+		if !reached {
+			// The line leaves the area at the previous point or enters it
+			// at this point (both are on the boundary)
+			if current != nil {
+				result = append(result, current)
+				current = nil
+			}
+			if inside {
+				current = []GPXPoint{*point}
+			}
+		}
+	}
+	if current != nil {
+		result = append(result, current)
+	}
+	return result
+}
+
+// Crop returns the parts of the segment within the area
+func (seg *GPXTrackSegment) Crop(area Area) []GPXTrackSegment {
+	result := make([]GPXTrackSegment, 0)
+	for _, points := range cropPoints(seg.Points, area) {
+		result = append(result, GPXTrackSegment{Points: points, Extensions: seg.Extensions})
+	}
+	return result
+}
+
+// Crop removes the parts of the track outside the area. Segments are split
+// where they leave and enter the area.
+func (trk *GPXTrack) Crop(area Area) {
+	segments := make([]GPXTrackSegment, 0)
+	for segmentNo := range trk.Segments {
+		segments = append(segments, trk.Segments[segmentNo].Crop(area)...)
+	}
+	trk.Segments = segments
+}
+
+// Crop returns the parts of the route within the area (every part is a new
+// route with the same name and other attributes)
+func (rte *GPXRoute) Crop(area Area) []GPXRoute {
+	result := make([]GPXRoute, 0)
+	for _, points := range cropPoints(rte.Points, area) {
+		part := *rte
+		part.Points = points
+		result = append(result, part)
+	}
+	return result
+}
+
+// Crop removes everything outside the area (for example *GpxBounds,
+// *Polygon or *Circle). Track segments and routes are split where they
+// leave and enter the area and the boundary crossing points are
+// interpolated. Tracks and routes without points in the area are removed.
+func (g *GPX) Crop(area Area) {
+	waypoints := make([]GPXPoint, 0)
+	for _, waypoint := range g.Waypoints {
+		if area.Contains(&waypoint) {
+			waypoints = append(waypoints, waypoint)
+		}
+	}
+	g.Waypoints = waypoints
+
+	routes := make([]GPXRoute, 0)
+	for routeNo := range g.Routes {
+		routes = append(routes, g.Routes[routeNo].Crop(area)...)
+	}
+	g.Routes = routes
+
+	tracks := make([]GPXTrack, 0)
+	for trackNo := range g.Tracks {
+		g.Tracks[trackNo].Crop(area)
+		if len(g.Tracks[trackNo].Segments) > 0 {
+			tracks = append(tracks, g.Tracks[trackNo])
+		}
+	}
+	g.Tracks = tracks
+}
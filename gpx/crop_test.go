@@ -0,0 +1,234 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAreaContains(t *testing.T) {
+	t.Parallel()
+
+	bounds := &GpxBounds{MinLatitude: 45, MaxLatitude: 46, MinLongitude: 13, MaxLongitude: 14}
+	assert.True(t, bounds.Contains(&Point{Latitude: 45.5, Longitude: 13.5}))
+	assert.True(t, bounds.Contains(&Point{Latitude: 45, Longitude: 13}))
+	assert.False(t, bounds.Contains(&Point{Latitude: 46.5, Longitude: 13.5}))
+
+	polygon := &Polygon{
+		// Triangle
+		Outer: []Point{{Latitude: 45, Longitude: 13}, {Latitude: 47, Longitude: 13}, {Latitude: 45, Longitude: 15}},
+		Holes: [][]Point{{{Latitude: 45.2, Longitude: 13.2}, {Latitude: 45.4, Longitude: 13.2}, {Latitude: 45.4, Longitude: 13.4}, {Latitude: 45.2, Longitude: 13.4}}},
+	}
+	assert.True(t, polygon.Contains(&Point{Latitude: 45.1, Longitude: 13.1}))
+	assert.True(t, polygon.Contains(&Point{Latitude: 45.5, Longitude: 14.4}))
+	assert.False(t, polygon.Contains(&Point{Latitude: 45.3, Longitude: 13.3}))
+	assert.False(t, polygon.Contains(&Point{Latitude: 46.5, Longitude: 14.5}))
+	assert.False(t, polygon.Contains(&Point{Latitude: 44, Longitude: 13.5}))
+
+	circle := &Circle{Center: Point{Latitude: 45, Longitude: 13}, Radius: 1000}
+	assert.True(t, circle.Contains(&Point{Latitude: 45 + 900/oneDegree, Longitude: 13}))
+	assert.False(t, circle.Contains(&Point{Latitude: 45 + 1100/oneDegree, Longitude: 13}))
+}
+
+func TestCropSegment(t *testing.T) {
+	t.Parallel()
+
+	// North 0..400m, back to 0 and north to 100m
+	seg := newTestSegment(10, 100, 100, 100, 100, -100, -100, -100, -100, 100)
+	circle := &Circle{Center: seg.Points[0].Point, Radius: 150}
+
+	parts := seg.Crop(circle)
+	assert.Equal(t, 2, len(parts))
+
+	assert.Equal(t, 3, len(parts[0].Points))
+	assert.Equal(t, seg.Points[0], parts[0].Points[0])
+	assert.InDelta(t, 150, parts[0].Points[2].Distance2D(&seg.Points[0]), 0.01)
+	// Interpolated time at the crossing:
+	assert.Equal(t, testStartTime.Add(15*time.Second), parts[0].Points[2].Timestamp.Round(1e6))
+
+	assert.Equal(t, 4, len(parts[1].Points))
+	assert.InDelta(t, 150, parts[1].Points[0].Distance2D(&seg.Points[0]), 0.01)
+	assert.Equal(t, seg.Points[9], parts[1].Points[3])
+
+	assert.Equal(t, 0, len(seg.Crop(&Circle{Center: Point{Latitude: 50, Longitude: 13}, Radius: 100})))
+	assert.Equal(t, 1, len(seg.Crop(&Circle{Center: seg.Points[0].Point, Radius: 1000})))
+	assert.Equal(t, len(seg.Points), len(seg.Crop(&Circle{Center: seg.Points[0].Point, Radius: 1000})[0].Points))
+}
+
+// testArea is an area without the areaPasses shortcuts
+type testArea struct {
+	Circle
+}
+
+func TestCropSparseLine(t *testing.T) {
+	t.Parallel()
+
+	// Both points are outside, the line between them crosses the areas:
+	seg := newTestSegment(10, 1000)
+	middle := interpolatePoints(&seg.Points[0], &seg.Points[1], 0.5)
+	circle := &Circle{Center: middle.Point, Radius: 30}
+
+	for _, area := range []Area{circle, &testArea{Circle: *circle}} {
+		parts := seg.Crop(area)
+		assert.Equal(t, 1, len(parts))
+		assert.Equal(t, 2, len(parts[0].Points))
+		assert.InDelta(t, 30, parts[0].Points[0].Distance2D(&middle), 0.01)
+		assert.InDelta(t, 30, parts[0].Points[1].Distance2D(&middle), 0.01)
+		assert.Equal(t, testStartTime.Add(4700*time.Millisecond), parts[0].Points[0].Timestamp.Round(time.Millisecond))
+		assert.Equal(t, testStartTime.Add(5300*time.Millisecond), parts[0].Points[1].Timestamp.Round(time.Millisecond))
+	}
+
+	// Passing by:
+	assert.Equal(t, 0, len(seg.Crop(&Circle{Center: newTestZigZagPoints(0, 0, 0, 0, 0, 40)[5].Point, Radius: 30})))
+
+	// Through a polygon with a hole (so two parts):
+	square := func(size float64) []Point {
+		delta := size / 2 / oneDegree
+		return []Point{
+			{Latitude: middle.Latitude - delta, Longitude: middle.Longitude - delta},
+			{Latitude: middle.Latitude + delta, Longitude: middle.Longitude - delta},
+			{Latitude: middle.Latitude + delta, Longitude: middle.Longitude + delta},
+			{Latitude: middle.Latitude - delta, Longitude: middle.Longitude + delta},
+		}
+	}
+	parts := seg.Crop(&Polygon{Outer: square(200), Holes: [][]Point{square(100)}})
+	assert.Equal(t, 2, len(parts))
+	assert.InDelta(t, 400, parts[0].Points[0].Distance2D(&seg.Points[0]), 0.01)
+	assert.InDelta(t, 450, parts[0].Points[1].Distance2D(&seg.Points[0]), 0.01)
+	assert.InDelta(t, 550, parts[1].Points[0].Distance2D(&seg.Points[0]), 0.01)
+	assert.InDelta(t, 600, parts[1].Points[1].Distance2D(&seg.Points[0]), 0.01)
+
+	bounds := &GpxBounds{MinLatitude: middle.Latitude - 0.001, MaxLatitude: middle.Latitude + 0.001, MinLongitude: 12, MaxLongitude: 14}
+	parts = seg.Crop(bounds)
+	assert.Equal(t, 1, len(parts))
+	assert.InDelta(t, bounds.MinLatitude, parts[0].Points[0].Latitude, 1e-7)
+	assert.InDelta(t, bounds.MaxLatitude, parts[0].Points[1].Latitude, 1e-7)
+}
+
+// gridPoint is a point 45°N 13°E moved x and y hundredths of a degree east
+// and north
+func gridPoint(x, y float64) Point {
+	return Point{Latitude: 45 + y/100, Longitude: 13 + x/100}
+}
+
+func gridSegment(xys ...float64) GPXTrackSegment {
+	var seg GPXTrackSegment
+	for n := 0; n+1 < len(xys); n += 2 {
+		seg.AppendPoint(&GPXPoint{Point: gridPoint(xys[n], xys[n+1])})
+	}
+	return seg
+}
+
+func assertGridParts(t *testing.T, expected [][]float64, parts []GPXTrackSegment) {
+	if !assert.Equal(t, len(expected), len(parts)) {
+		return
+	}
+	for partNo := range expected {
+		if !assert.Equal(t, len(expected[partNo])/2, len(parts[partNo].Points), "part %d", partNo) {
+			continue
+		}
+		for pointNo := range parts[partNo].Points {
+			point := gridPoint(expected[partNo][2*pointNo], expected[partNo][2*pointNo+1])
+			assert.InDelta(t, point.Latitude, parts[partNo].Points[pointNo].Latitude, 1e-7, "part %d point %d", partNo, pointNo)
+			assert.InDelta(t, point.Longitude, parts[partNo].Points[pointNo].Longitude, 1e-7, "part %d point %d", partNo, pointNo)
+		}
+	}
+}
+
+func TestCropConcavePolygon(t *testing.T) {
+	t.Parallel()
+
+	// A "C" open to the east, x=2 is inside for 0<=y<=1 and 2<=y<=3:
+	c := &Polygon{Outer: []Point{
+		gridPoint(0, 0), gridPoint(4, 0), gridPoint(4, 1), gridPoint(1, 1),
+		gridPoint(1, 2), gridPoint(4, 2), gridPoint(4, 3), gridPoint(0, 3),
+	}}
+
+	// Both points inside, but the line crosses the opening:
+	seg := gridSegment(2, 0.5, 2, 2.5, 3, 2.5)
+	assertGridParts(t, [][]float64{{2, 0.5, 2, 1}, {2, 2, 2, 2.5, 3, 2.5}}, seg.Crop(c))
+
+	// Inside to outside, leaving, entering and leaving again:
+	seg = gridSegment(2, 0.5, 2, 3.5)
+	assertGridParts(t, [][]float64{{2, 0.5, 2, 1}, {2, 2, 2, 3}}, seg.Crop(c))
+
+	// Outside to inside:
+	seg = gridSegment(2, 3.5, 2, 0.5)
+	assertGridParts(t, [][]float64{{2, 3, 2, 2}, {2, 1, 2, 0.5}}, seg.Crop(c))
+
+	// Outside to outside, through both arms:
+	seg = gridSegment(2, -1, 2, 3.5)
+	assertGridParts(t, [][]float64{{2, 0, 2, 1}, {2, 2, 2, 3}}, seg.Crop(c))
+}
+
+func TestCropPolygonWithHole(t *testing.T) {
+	t.Parallel()
+
+	polygon := &Polygon{
+		Outer: []Point{gridPoint(0, 0), gridPoint(4, 0), gridPoint(4, 4), gridPoint(0, 4)},
+		Holes: [][]Point{{gridPoint(1, 1), gridPoint(3, 1), gridPoint(3, 3), gridPoint(1, 3)}},
+	}
+
+	// Both points inside, the line crosses the hole:
+	seg := gridSegment(0.5, 0.5, 0.5, 2, 3.5, 2, 3.5, 3.5)
+	assertGridParts(t, [][]float64{{0.5, 0.5, 0.5, 2, 1, 2}, {3, 2, 3.5, 2, 3.5, 3.5}}, seg.Crop(polygon))
+
+	// Into the hole and out of the polygon:
+	seg = gridSegment(0.5, 2, 2, 2, 5, 2)
+	assertGridParts(t, [][]float64{{0.5, 2, 1, 2}, {3, 2, 4, 2}}, seg.Crop(polygon))
+}
+
+func TestCropAcrossAntimeridian(t *testing.T) {
+	t.Parallel()
+
+	seg := GPXTrackSegment{Points: []GPXPoint{
+		{Point: Point{Latitude: 10, Longitude: 179.99}},
+		{Point: Point{Latitude: 10, Longitude: -179.99}},
+	}}
+	parts := seg.Crop(&Circle{Center: Point{Latitude: 10, Longitude: 180}, Radius: 100})
+	assert.Equal(t, 1, len(parts))
+	assert.Equal(t, 2, len(parts[0].Points))
+	assert.InDelta(t, 179.9991, parts[0].Points[0].Longitude, 1e-4)
+	assert.InDelta(t, -179.9991, parts[0].Points[1].Longitude, 1e-4)
+}
+
+func TestCropGPX(t *testing.T) {
+	t.Parallel()
+
+	points := newTestZigZagPoints(0, 0, 0, 0, 0)
+	g := GPX{
+		Waypoints: []GPXPoint{points[1], points[4]},
+		Routes:    []GPXRoute{{Name: "Route", Points: newTestZigZagPoints(0, 0, 1000, 0)}, {Points: points[3:]}},
+		Tracks: []GPXTrack{
+			{Name: "Inside", Segments: []GPXTrackSegment{{Points: points}}},
+			{Name: "Outside", Segments: []GPXTrackSegment{{Points: newTestZigZagPoints(1000, 1000)}}},
+		},
+	}
+	bounds := &GpxBounds{MinLatitude: 44, MaxLatitude: points[3].Latitude + 50/oneDegree, MinLongitude: 12.9, MaxLongitude: 13.005}
+
+	g.Crop(bounds)
+
+	assert.Equal(t, []GPXPoint{points[1]}, g.Waypoints)
+
+	assert.Equal(t, 1, len(g.Tracks))
+	assert.Equal(t, "Inside", g.Tracks[0].Name)
+	assert.Equal(t, 1, len(g.Tracks[0].Segments))
+	assert.Equal(t, 5, len(g.Tracks[0].Segments[0].Points))
+	assert.InDelta(t, bounds.MaxLatitude, g.Tracks[0].Segments[0].Points[4].Latitude, 1e-7)
+
+	// The first route goes out of the bounds (to the east) and back:
+	assert.Equal(t, 3, len(g.Routes))
+	assert.Equal(t, "Route", g.Routes[0].Name)
+	assert.Equal(t, "Route", g.Routes[1].Name)
+	assert.Equal(t, 3, len(g.Routes[0].Points))
+	assert.InDelta(t, 13.005, g.Routes[0].Points[2].Longitude, 1e-7)
+	assert.Equal(t, 2, len(g.Routes[1].Points))
+	assert.InDelta(t, 13.005, g.Routes[1].Points[0].Longitude, 1e-7)
+	assert.Equal(t, 2, len(g.Routes[2].Points))
+}
@@ -0,0 +1,118 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"time"
+)
+
+// pointAtTime interpolates the point at time t between two points with timestamps
+func pointAtTime(point1, point2 *GPXPoint, t time.Time) GPXPoint {
+	var ratio float64
+	if delta := point2.Timestamp.Sub(point1.Timestamp); delta > 0 {
+		ratio = float64(t.Sub(point1.Timestamp)) / float64(delta)
+	}
+	result := interpolatePoints(point1, point2, ratio)
+	result.Timestamp = t
+	return result
+}
+
+// CropTime returns the part of the segment between start and end (a zero
+// time is no limit). Points are interpolated at start and end if they are
+// between two points, and points without timestamps are removed. The result
+// is empty if start is after end and has (at most) one point if they are
+// equal.
+func (seg *GPXTrackSegment) CropTime(start, end time.Time) GPXTrackSegment {
+	result := GPXTrackSegment{Points: make([]GPXPoint, 0), Extensions: seg.Extensions}
+	if !start.IsZero() && !end.IsZero() && start.After(end) {
+		return result
+	}
+	var previous *GPXPoint
+	for pointNo := range seg.Points {
+		point := &seg.Points[pointNo]
+		if !hasTimestamp(point) {
+			continue
+		}
+		if previous != nil {
+			if !start.IsZero() && previous.Timestamp.Before(start) && point.Timestamp.After(start) {
+				result.Points = append(result.Points, pointAtTime(previous, point, start))
+			}
+			// Only one point if start and end are equal:
+			if !end.IsZero() && !end.Equal(start) && previous.Timestamp.Before(end) && point.Timestamp.After(end) {
+				result.Points = append(result.Points, pointAtTime(previous, point, end))
+			}
+		}
+		if (start.IsZero() || !point.Timestamp.Before(start)) && (end.IsZero() || !point.Timestamp.After(end)) {
+			result.Points = append(result.Points, *point)
+		}
+		previous = point
+	}
+	return result
+}
+
+// CropTime removes the parts of the track outside the time range (a zero
+// time is no limit). Segments without points in the range are removed.
+func (trk *GPXTrack) CropTime(start, end time.Time) {
+	segments := make([]GPXTrackSegment, 0)
+	for segmentNo := range trk.Segments {
+		if segment := trk.Segments[segmentNo].CropTime(start, end); len(segment.Points) > 0 {
+			segments = append(segments, segment)
+		}
+	}
+	trk.Segments = segments
+}
+
+// CropTime removes the parts of all tracks outside the time range (a zero
+// time is no limit). Tracks without points in the range are removed,
+// waypoints and routes are not changed.
+func (g *GPX) CropTime(start, end time.Time) {
+	tracks := make([]GPXTrack, 0)
+	for trackNo := range g.Tracks {
+		g.Tracks[trackNo].CropTime(start, end)
+		if len(g.Tracks[trackNo].Segments) > 0 {
+			tracks = append(tracks, g.Tracks[trackNo])
+		}
+	}
+	g.Tracks = tracks
+}
+
+// trimIdleStart removes the points of the stop at the segment start (the
+// point where the movement starts is kept)
+func (seg *GPXTrackSegment) trimIdleStart(opts MovingOptions) {
+	if stops := seg.AutoPause(opts); len(stops) > 0 && stops[0].Start.PointNo == 0 {
+		seg.Points = seg.Points[stops[0].End.PointNo:]
+	}
+}
+
+// trimIdleEnd removes the points of the stop at the segment end
+func (seg *GPXTrackSegment) trimIdleEnd(opts MovingOptions) {
+	if stops := seg.AutoPause(opts); len(stops) > 0 && stops[len(stops)-1].End.PointNo == len(seg.Points)-1 {
+		seg.Points = seg.Points[:stops[len(stops)-1].Start.PointNo+1]
+	}
+}
+
+// TrimIdle removes the stops (see AutoPause) at the start and end of the segment
+func (seg *GPXTrackSegment) TrimIdle(opts MovingOptions) {
+	seg.trimIdleStart(opts)
+	seg.trimIdleEnd(opts)
+}
+
+// TrimIdle removes the stops (see AutoPause) at the start of the first and
+// the end of the last segment
+func (trk *GPXTrack) TrimIdle(opts MovingOptions) {
+	if len(trk.Segments) == 0 {
+		return
+	}
+	trk.Segments[0].trimIdleStart(opts)
+	trk.Segments[len(trk.Segments)-1].trimIdleEnd(opts)
+}
+
+// TrimIdle removes the stops at the start and end of every track
+func (g *GPX) TrimIdle(opts MovingOptions) {
+	for trackNo := range g.Tracks {
+		g.Tracks[trackNo].TrimIdle(opts)
+	}
+}
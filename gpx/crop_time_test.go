@@ -0,0 +1,96 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCropTimeSegment(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 100, 100, 100)
+
+	cropped := seg.CropTime(testStartTime.Add(15*time.Second), testStartTime.Add(30*time.Second))
+	assert.Equal(t, 3, len(cropped.Points))
+	assert.Equal(t, testStartTime.Add(15*time.Second), cropped.Points[0].Timestamp)
+	assert.InDelta(t, 150, cropped.Points[0].Distance2D(&seg.Points[0]), 0.01)
+	assert.Equal(t, seg.Points[2], cropped.Points[1])
+	assert.Equal(t, seg.Points[3], cropped.Points[2])
+
+	// Both limits between the same two points:
+	cropped = seg.CropTime(testStartTime.Add(12*time.Second), testStartTime.Add(18*time.Second))
+	assert.Equal(t, 2, len(cropped.Points))
+	assert.InDelta(t, 120, cropped.Points[0].Distance2D(&seg.Points[0]), 0.01)
+	assert.InDelta(t, 180, cropped.Points[1].Distance2D(&seg.Points[0]), 0.01)
+
+	// No limits:
+	assert.Equal(t, seg.Points, seg.CropTime(time.Time{}, time.Time{}).Points)
+	assert.Equal(t, 3, len(seg.CropTime(time.Time{}, testStartTime.Add(20*time.Second)).Points))
+	assert.Equal(t, 0, len(seg.CropTime(testStartTime.Add(time.Hour), time.Time{}).Points))
+
+	// Start after end:
+	assert.Equal(t, 0, len(seg.CropTime(testStartTime.Add(18*time.Second), testStartTime.Add(12*time.Second)).Points))
+	assert.Equal(t, 0, len(seg.CropTime(testStartTime.Add(30*time.Second), testStartTime.Add(10*time.Second)).Points))
+
+	// Start and end are equal:
+	cropped = seg.CropTime(testStartTime.Add(15*time.Second), testStartTime.Add(15*time.Second))
+	assert.Equal(t, 1, len(cropped.Points))
+	assert.Equal(t, testStartTime.Add(15*time.Second), cropped.Points[0].Timestamp)
+	cropped = seg.CropTime(testStartTime.Add(20*time.Second), testStartTime.Add(20*time.Second))
+	assert.Equal(t, []GPXPoint{seg.Points[2]}, cropped.Points)
+}
+
+func TestCropTimeGPX(t *testing.T) {
+	t.Parallel()
+
+	first := newTestSegment(10, 100, 100)
+	second := newTestSegment(10, 100, 100)
+	for pointNo := range second.Points {
+		second.Points[pointNo].Timestamp = second.Points[pointNo].Timestamp.Add(time.Minute)
+	}
+	g := GPX{Tracks: []GPXTrack{
+		{Segments: []GPXTrackSegment{first, second}},
+		{Segments: []GPXTrackSegment{newTestSegment(10, 100)}},
+	}}
+	g.Tracks[1].Segments[0].Points[0].Timestamp = testStartTime.Add(-time.Hour)
+	g.Tracks[1].Segments[0].Points[1].Timestamp = testStartTime.Add(-time.Hour + time.Second)
+
+	g.CropTime(testStartTime.Add(5*time.Second), testStartTime.Add(65*time.Second))
+	assert.Equal(t, 1, len(g.Tracks))
+	assert.Equal(t, 2, len(g.Tracks[0].Segments))
+	assert.Equal(t, 3, len(g.Tracks[0].Segments[0].Points))
+	assert.Equal(t, 2, len(g.Tracks[0].Segments[1].Points))
+	assert.Equal(t, testStartTime.Add(65*time.Second), g.Tracks[0].Segments[1].Points[1].Timestamp)
+}
+
+func TestTrimIdle(t *testing.T) {
+	t.Parallel()
+
+	opts := MovingOptions{MinStopDuration: 20 * time.Second}
+	seg := newTestSegment(10, 0, 0, 0, 100, 100, 0, 0, 0)
+
+	trimmed := seg
+	trimmed.TrimIdle(opts)
+	assert.Equal(t, seg.Points[3:6], trimmed.Points)
+
+	// Stops shorter than MinStopDuration are kept:
+	trimmed = seg
+	trimmed.TrimIdle(MovingOptions{MinStopDuration: time.Minute})
+	assert.Equal(t, seg.Points, trimmed.Points)
+
+	g := GPX{Tracks: []GPXTrack{{Segments: []GPXTrackSegment{
+		newTestSegment(10, 0, 0, 0, 100),
+		newTestSegment(10, 0, 0, 0, 100, 0, 0, 0),
+	}}}}
+	g.TrimIdle(opts)
+	assert.Equal(t, 2, len(g.Tracks[0].Segments[0].Points))
+	// The start of the second segment is not trimmed:
+	assert.Equal(t, 5, len(g.Tracks[0].Segments[1].Points))
+}
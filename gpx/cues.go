@@ -0,0 +1,221 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// TurnDirection is a turn classification
+type TurnDirection int
+
+const (
+	TurnSlightLeft TurnDirection = iota
+	TurnLeft
+	TurnSharpLeft
+	TurnSlightRight
+	TurnRight
+	TurnSharpRight
+	TurnUTurn
+)
+
+// String returns the turn name (also used as the route point symbol, the
+// same as the Garmin course point types)
+func (td TurnDirection) String() string {
+	switch td {
+	case TurnSlightLeft:
+		return "Slight Left"
+	case TurnLeft:
+		return "Left"
+	case TurnSharpLeft:
+		return "Sharp Left"
+	case TurnSlightRight:
+		return "Slight Right"
+	case TurnRight:
+		return "Right"
+	case TurnSharpRight:
+		return "Sharp Right"
+	case TurnUTurn:
+		return "U Turn"
+	}
+	return ""
+}
+
+// turnDirection classifies the turn angle (degrees, positive to the right):
+// slight up to 60, normal up to 120, sharp up to 160 and U-turn above.
+func turnDirection(angle float64) TurnDirection {
+	abs := math.Abs(angle)
+	switch {
+	case abs >= 160:
+		return TurnUTurn
+	case abs >= 120 && angle < 0:
+		return TurnSharpLeft
+	case abs >= 120:
+		return TurnSharpRight
+	case abs >= 60 && angle < 0:
+		return TurnLeft
+	case abs >= 60:
+		return TurnRight
+	case angle < 0:
+		return TurnSlightLeft
+	}
+	return TurnSlightRight
+}
+
+// CueOptions contains the turn detection settings. Zero fields are replaced
+// with the values from DefaultCueOptions.
+type CueOptions struct {
+	// Window is the distance (in meters) before and after a point used to
+	// compute the incoming and outgoing bearings
+	Window float64
+	// MinAngle is the smallest bearing change (in degrees) reported as a turn
+	MinAngle float64
+}
+
+// DefaultCueOptions are used for zero CueOptions fields
+var DefaultCueOptions = CueOptions{
+	Window:   30,
+	MinAngle: 30,
+}
+
+func (opts CueOptions) withDefaults() CueOptions {
+	if opts.Window <= 0 {
+		opts.Window = DefaultCueOptions.Window
+	}
+	if opts.MinAngle <= 0 {
+		opts.MinAngle = DefaultCueOptions.MinAngle
+	}
+	return opts
+}
+
+// Cue is a turn on a route or track
+type Cue struct {
+	Turn TurnDirection
+	// Angle is the bearing change in degrees (positive to the right)
+	Angle float64
+	// Distance is the 2D distance (in meters) from the start
+	Distance float64
+	// SegmentNo is always 0 for routes
+	SegmentNo int
+	PointNo   int
+	Point     GPXPoint
+}
+
+// locationAtDistance returns the interpolated location at the distance from start
+func locationAtDistance(points []GPXPoint, distances []float64, distance float64) Point {
+	k := sort.SearchFloat64s(distances, distance)
+	if k >= len(points) {
+		return points[len(points)-1].Point
+	}
+	if k == 0 || distances[k] == distance {
+		return points[k].Point
+	}
+	ratio := (distance - distances[k-1]) / (distances[k] - distances[k-1])
+	return interpolatePoints(&points[k-1], &points[k], ratio).Point
+}
+
+// findCues returns the turns on the points, distances are the distances
+// from the start for every point (see cumulativeDistances)
+func findCues(points []GPXPoint, distances []float64, opts CueOptions) []Cue {
+	opts = opts.withDefaults()
+
+	candidates := make([]Cue, 0)
+	for pointNo := range points {
+		distance := distances[pointNo]
+		if distance-opts.Window < 0 || distance+opts.Window > distances[len(distances)-1] {
+			continue
+		}
+		if pointNo > 0 && distances[pointNo-1] == distance {
+			// Duplicate point
+			continue
+		}
+		before := locationAtDistance(points, distances, distance-opts.Window)
+		after := locationAtDistance(points, distances, distance+opts.Window)
+		in := AngleFromNorth(before, points[pointNo].Point, false)
+		out := AngleFromNorth(points[pointNo].Point, after, false)
+		angle := math.Mod(out-in+540, 360) - 180
+		if math.IsNaN(angle) || math.Abs(angle) < opts.MinAngle {
+			continue
+		}
+		candidates = append(candidates, Cue{Turn: turnDirection(angle), Angle: angle, Distance: distance, PointNo: pointNo, Point: points[pointNo]})
+	}
+
+	// Neighbouring points of the same turn (closer than the window, in the
+	// same direction) are merged into the sharpest one:
+	result := make([]Cue, 0)
+	for _, candidate := range candidates {
+		if len(result) > 0 {
+			last := &result[len(result)-1]
+			if candidate.Distance-last.Distance <= opts.Window && (candidate.Angle > 0) == (last.Angle > 0) {
+				if math.Abs(candidate.Angle) > math.Abs(last.Angle) {
+					*last = candidate
+				}
+				continue
+			}
+		}
+		result = append(result, candidate)
+	}
+	return result
+}
+
+// Cues returns the turns on the route
+func (rte *GPXRoute) Cues(opts CueOptions) []Cue {
+	return findCues(rte.Points, cumulativeDistances(rte.Points), opts)
+}
+
+// Cues returns the turns on the track (segments are joined, but gaps between
+// segments are not counted in the cue distances)
+func (trk *GPXTrack) Cues(opts CueOptions) []Cue {
+	points := make([]GPXPoint, 0, trk.GetTrackPointsNo())
+	distances := make([]float64, 0, trk.GetTrackPointsNo())
+	segmentNos := make([]int, 0, trk.GetTrackPointsNo())
+	pointNos := make([]int, 0, trk.GetTrackPointsNo())
+	var fromStart float64
+	for segmentNo, seg := range trk.Segments {
+		for pointNo := range seg.Points {
+			if pointNo > 0 {
+				fromStart += seg.Points[pointNo].Distance2D(&seg.Points[pointNo-1])
+			}
+			points = append(points, seg.Points[pointNo])
+			distances = append(distances, fromStart)
+			segmentNos = append(segmentNos, segmentNo)
+			pointNos = append(pointNos, pointNo)
+		}
+	}
+	result := findCues(points, distances, opts)
+	for cueNo := range result {
+		n := result[cueNo].PointNo
+		result[cueNo].SegmentNo, result[cueNo].PointNo = segmentNos[n], pointNos[n]
+	}
+	return result
+}
+
+// CueSheet formats the cues as a text table
+func CueSheet(cues []Cue) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%4s %10s  %-12s  %s\n", "#", "km", "Turn", "Name")
+	for cueNo, cue := range cues {
+		fmt.Fprintf(&buf, "%4d %10.2f  %-12s  %s\n", cueNo+1, cue.Distance/1000, cue.Turn, cue.Point.Name)
+	}
+	return buf.String()
+}
+
+// CuesToRoutePoints returns a route point for every cue with the turn as
+// symbol and the distance and turn as name
+func CuesToRoutePoints(cues []Cue) []GPXPoint {
+	result := make([]GPXPoint, len(cues))
+	for cueNo, cue := range cues {
+		result[cueNo].Point = cue.Point.Point
+		result[cueNo].Timestamp = cue.Point.Timestamp
+		result[cueNo].Symbol = cue.Turn.String()
+		result[cueNo].Name = fmt.Sprintf("%.1f km %s", cue.Distance/1000, cue.Turn)
+		result[cueNo].Description = cue.Point.Name
+	}
+	return result
+}
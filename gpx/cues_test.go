@@ -0,0 +1,120 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestPath returns points every 10 meters, going n steps in every bearing
+func newTestPath(n int, bearings ...float64) []GPXPoint {
+	point := GPXPoint{Point: Point{Latitude: 45, Longitude: 13}}
+	result := []GPXPoint{point}
+	for _, bearing := range bearings {
+		for i := 0; i < n; i++ {
+			point.Latitude += 10 * math.Cos(ToRad(bearing)) / oneDegree
+			point.Longitude += 10 * math.Sin(ToRad(bearing)) / oneDegree / math.Cos(ToRad(point.Latitude))
+			result = append(result, point)
+		}
+	}
+	return result
+}
+
+func TestTurnDirection(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, TurnSlightRight, turnDirection(40))
+	assert.Equal(t, TurnSlightLeft, turnDirection(-40))
+	assert.Equal(t, TurnRight, turnDirection(90))
+	assert.Equal(t, TurnLeft, turnDirection(-90))
+	assert.Equal(t, TurnSharpRight, turnDirection(130))
+	assert.Equal(t, TurnSharpLeft, turnDirection(-130))
+	assert.Equal(t, TurnUTurn, turnDirection(175))
+	assert.Equal(t, TurnUTurn, turnDirection(-175))
+	assert.Equal(t, "Sharp Left", TurnSharpLeft.String())
+}
+
+func TestRouteCues(t *testing.T) {
+	t.Parallel()
+
+	// North, right (east), slight left, sharp right, straight, U-turn:
+	rte := GPXRoute{Points: newTestPath(10, 0, 90, 45, 180, 180, 0)}
+	rte.Points[20].Name = "Church"
+	cues := rte.Cues(CueOptions{})
+	turns := make([]TurnDirection, len(cues))
+	for cueNo := range cues {
+		turns[cueNo] = cues[cueNo].Turn
+	}
+	assert.Equal(t, []TurnDirection{TurnRight, TurnSlightLeft, TurnSharpRight, TurnUTurn}, turns)
+	if assert.Equal(t, 4, len(cues)) {
+		assert.Equal(t, 10, cues[0].PointNo)
+		assert.InDelta(t, 100, cues[0].Distance, 0.5)
+		assert.InDelta(t, 90, cues[0].Angle, 1)
+		assert.Equal(t, 20, cues[1].PointNo)
+		assert.InDelta(t, -45, cues[1].Angle, 1)
+		assert.Equal(t, 30, cues[2].PointNo)
+		assert.InDelta(t, 135, cues[2].Angle, 1)
+		// On a U-turn on the same line the neighbouring points are U-turns, too:
+		assert.InDelta(t, 50, cues[3].PointNo, 1)
+	}
+
+	// A bigger minimal angle ignores the slight turn:
+	assert.Equal(t, 3, len(rte.Cues(CueOptions{MinAngle: 50})))
+
+	sheet := CueSheet(cues)
+	lines := strings.Split(strings.TrimSpace(sheet), "\n")
+	assert.Equal(t, 5, len(lines))
+	assert.Equal(t, "   2       0.20  Slight Left   Church", lines[2])
+
+	routePoints := CuesToRoutePoints(cues)
+	if assert.Equal(t, 4, len(routePoints)) {
+		assert.Equal(t, "Slight Left", routePoints[1].Symbol)
+		assert.Equal(t, "0.2 km Slight Left", routePoints[1].Name)
+		assert.Equal(t, "Church", routePoints[1].Description)
+		assert.Equal(t, rte.Points[20].Point, routePoints[1].Point)
+	}
+}
+
+func TestTrackCues(t *testing.T) {
+	t.Parallel()
+
+	points := newTestPath(10, 0, 90, 0)
+	trk := GPXTrack{}
+	trk.AppendSegment(&GPXTrackSegment{Points: points[:15]})
+	trk.AppendSegment(&GPXTrackSegment{Points: points[15:]})
+	cues := trk.Cues(CueOptions{})
+	if assert.Equal(t, 2, len(cues)) {
+		assert.Equal(t, TurnRight, cues[0].Turn)
+		assert.Equal(t, 0, cues[0].SegmentNo)
+		assert.Equal(t, 10, cues[0].PointNo)
+		assert.InDelta(t, 100, cues[0].Distance, 0.5)
+		assert.Equal(t, TurnLeft, cues[1].Turn)
+		assert.Equal(t, 1, cues[1].SegmentNo)
+		assert.Equal(t, 5, cues[1].PointNo)
+		// Without the 10m gap between the segments:
+		assert.InDelta(t, 190, cues[1].Distance, 0.5)
+	}
+
+	// A 1km gap (to the north) between the segments isn't counted:
+	points = newTestPath(10, 0, 0, 90)
+	trk = GPXTrack{}
+	trk.AppendSegment(&GPXTrackSegment{Points: points[:11]})
+	trk.AppendSegment(&GPXTrackSegment{Points: points[11:]})
+	for pointNo := range trk.Segments[1].Points {
+		trk.Segments[1].Points[pointNo].Latitude += 1000 / oneDegree
+	}
+	cues = trk.Cues(CueOptions{})
+	if assert.Equal(t, 1, len(cues)) {
+		assert.Equal(t, TurnRight, cues[0].Turn)
+		assert.Equal(t, 1, cues[0].SegmentNo)
+		assert.Equal(t, 9, cues[0].PointNo)
+		assert.InDelta(t, 190, cues[0].Distance, 0.5)
+	}
+}
@@ -0,0 +1,588 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JPEG markers
+const (
+	jpegMarkerSOI  = 0xD8
+	jpegMarkerSOS  = 0xDA
+	jpegMarkerEOI  = 0xD9
+	jpegMarkerAPP0 = 0xE0
+	jpegMarkerAPP1 = 0xE1
+)
+
+// EXIF/TIFF tags used for geotagging
+const (
+	exifTagDateTime           = 0x0132
+	exifTagExifIFD            = 0x8769
+	exifTagGPSIFD             = 0x8825
+	exifTagInteropIFD         = 0xA005
+	exifTagThumbnailOffset    = 0x0201
+	exifTagThumbnailLength    = 0x0202
+	exifTagDateTimeOriginal   = 0x9003
+	exifTagOffsetTimeOriginal = 0x9011
+	exifTagGPSVersionID       = 0x0000
+	exifTagGPSLatitudeRef     = 0x0001
+	exifTagGPSLatitude        = 0x0002
+	exifTagGPSLongitudeRef    = 0x0003
+	exifTagGPSLongitude       = 0x0004
+	exifTagGPSAltitudeRef     = 0x0005
+	exifTagGPSAltitude        = 0x0006
+	exifTagGPSTimeStamp       = 0x0007
+	exifTagGPSDateStamp       = 0x001D
+)
+
+// EXIF value types
+const (
+	exifTypeByte     = 1
+	exifTypeASCII    = 2
+	exifTypeShort    = 3
+	exifTypeLong     = 4
+	exifTypeRational = 5
+)
+
+const (
+	exifHeader                 = "Exif\x00\x00"
+	exifDateTimeLayout         = "2006:01:02 15:04:05"
+	exifMaxSegmentLength       = 0xFFFF - 2
+	exifDefaultRationalDivisor = 1000000
+)
+
+var exifTypeSizes = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// PhotoExif contains the EXIF data used for geotagging photos
+type PhotoExif struct {
+	// Time when the photo was taken. Cameras usually don't store the time
+	// zone, in that case HasTimeZone is false and the time is the camera wall
+	// clock in UTC.
+	Time        NullableTime
+	HasTimeZone bool
+
+	Latitude  NullableFloat64
+	Longitude NullableFloat64
+	Elevation NullableFloat64
+}
+
+type exifEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	// Raw value (in the byte order of the file)
+	data []byte
+	// Sub IFD (for Exif, GPS and Interoperability pointers)
+	sub *exifIFD
+}
+
+type exifIFD struct {
+	entries []exifEntry
+	// Thumbnail image (only in IFD1)
+	thumbnail []byte
+}
+
+func (ifd *exifIFD) get(tag uint16) *exifEntry {
+	if ifd == nil {
+		return nil
+	}
+	for entryNo := range ifd.entries {
+		if ifd.entries[entryNo].tag == tag {
+			return &ifd.entries[entryNo]
+		}
+	}
+	return nil
+}
+
+func (ifd *exifIFD) set(entry exifEntry) {
+	for entryNo := range ifd.entries {
+		if ifd.entries[entryNo].tag == entry.tag {
+			ifd.entries[entryNo] = entry
+			return
+		}
+	}
+	ifd.entries = append(ifd.entries, entry)
+}
+
+func (ifd *exifIFD) remove(tag uint16) {
+	entries := make([]exifEntry, 0, len(ifd.entries))
+	for _, entry := range ifd.entries {
+		if entry.tag != tag {
+			entries = append(entries, entry)
+		}
+	}
+	ifd.entries = entries
+}
+
+type exifData struct {
+	order binary.ByteOrder
+	ifd0  *exifIFD
+	ifd1  *exifIFD
+}
+
+// ----------------------------------------------------------------------------------------------------
+// TIFF parsing
+// ----------------------------------------------------------------------------------------------------
+
+func parseTIFF(tiff []byte) (*exifData, error) {
+	if len(tiff) < 8 {
+		return nil, errors.New("invalid EXIF data")
+	}
+	result := new(exifData)
+	switch string(tiff[:2]) {
+	case "II":
+		result.order = binary.LittleEndian
+	case "MM":
+		result.order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid EXIF byte order")
+	}
+	if result.order.Uint16(tiff[2:]) != 42 {
+		return nil, errors.New("invalid TIFF header")
+	}
+
+	var next uint32
+	var err error
+	result.ifd0, next, err = parseIFD(tiff, result.order, result.order.Uint32(tiff[4:]), 0)
+	if err != nil {
+		return nil, err
+	}
+	if next > 0 {
+		// IFD1 is optional (thumbnail), ignore it if broken:
+		if ifd1, _, err := parseIFD(tiff, result.order, next, 0); err == nil {
+			result.ifd1 = ifd1
+		}
+	}
+	return result, nil
+}
+
+func parseIFD(tiff []byte, order binary.ByteOrder, offset uint32, depth int) (*exifIFD, uint32, error) {
+	if depth > 4 {
+		return nil, 0, errors.New("too many nested EXIF IFDs")
+	}
+	if int(offset)+2 > len(tiff) {
+		return nil, 0, errors.New("invalid EXIF IFD offset")
+	}
+	entriesNo := int(order.Uint16(tiff[offset:]))
+	end := int(offset) + 2 + 12*entriesNo
+	if end+4 > len(tiff) {
+		return nil, 0, errors.New("invalid EXIF IFD")
+	}
+
+	result := new(exifIFD)
+	for entryNo := 0; entryNo < entriesNo; entryNo++ {
+		raw := tiff[int(offset)+2+12*entryNo:]
+		entry := exifEntry{
+			tag:   order.Uint16(raw[0:]),
+			typ:   order.Uint16(raw[2:]),
+			count: order.Uint32(raw[4:]),
+		}
+		typeSize, found := exifTypeSizes[entry.typ]
+		if !found {
+			continue
+		}
+		size := uint64(typeSize) * uint64(entry.count)
+		if size <= 4 {
+			entry.data = append([]byte{}, raw[8:8+size]...)
+		} else {
+			dataOffset := uint64(order.Uint32(raw[8:]))
+			if dataOffset+size > uint64(len(tiff)) {
+				continue
+			}
+			entry.data = append([]byte{}, tiff[dataOffset:dataOffset+size]...)
+		}
+
+		switch entry.tag {
+		case exifTagExifIFD, exifTagGPSIFD, exifTagInteropIFD:
+			if len(entry.data) < 4 {
+				continue
+			}
+			sub, _, err := parseIFD(tiff, order, order.Uint32(entry.data), depth+1)
+			if err != nil {
+				continue
+			}
+			entry.sub = sub
+		}
+		result.entries = append(result.entries, entry)
+	}
+
+	// Thumbnail:
+	thumbnailOffset, thumbnailLength := result.get(exifTagThumbnailOffset), result.get(exifTagThumbnailLength)
+	if thumbnailOffset != nil && thumbnailLength != nil {
+		start, length := uint64(exifUint(order, thumbnailOffset)), uint64(exifUint(order, thumbnailLength))
+		if start+length <= uint64(len(tiff)) {
+			result.thumbnail = append([]byte{}, tiff[start:start+length]...)
+		}
+	}
+
+	return result, order.Uint32(tiff[end:]), nil
+}
+
+func exifUint(order binary.ByteOrder, entry *exifEntry) uint32 {
+	switch {
+	case entry.typ == exifTypeShort && len(entry.data) >= 2:
+		return uint32(order.Uint16(entry.data))
+	case entry.typ == exifTypeLong && len(entry.data) >= 4:
+		return order.Uint32(entry.data)
+	}
+	return 0
+}
+
+func exifString(entry *exifEntry) string {
+	if entry == nil || entry.typ != exifTypeASCII {
+		return ""
+	}
+	return strings.TrimRight(string(entry.data), "\x00 ")
+}
+
+func exifRationals(order binary.ByteOrder, entry *exifEntry) []float64 {
+	if entry == nil || entry.typ != exifTypeRational {
+		return nil
+	}
+	result := make([]float64, 0, len(entry.data)/8)
+	for i := 0; i+8 <= len(entry.data); i += 8 {
+		numerator, denominator := order.Uint32(entry.data[i:]), order.Uint32(entry.data[i+4:])
+		if denominator == 0 {
+			result = append(result, 0)
+		} else {
+			result = append(result, float64(numerator)/float64(denominator))
+		}
+	}
+	return result
+}
+
+func (ed *exifData) photoExif() *PhotoExif {
+	result := new(PhotoExif)
+
+	exifIfd := ed.ifd0.get(exifTagExifIFD)
+	var dateTime, offset string
+	if exifIfd != nil {
+		dateTime = exifString(exifIfd.sub.get(exifTagDateTimeOriginal))
+		offset = exifString(exifIfd.sub.get(exifTagOffsetTimeOriginal))
+	}
+	if dateTime == "" {
+		dateTime = exifString(ed.ifd0.get(exifTagDateTime))
+	}
+	if t, err := time.Parse(exifDateTimeLayout, dateTime); err == nil {
+		if zoned, err := time.Parse(exifDateTimeLayout+"-07:00", dateTime+offset); err == nil && offset != "" {
+			t = zoned
+			result.HasTimeZone = true
+		}
+		result.Time.SetValue(t)
+	}
+
+	if gps := ed.ifd0.get(exifTagGPSIFD); gps != nil && gps.sub != nil {
+		latitude := exifRationals(ed.order, gps.sub.get(exifTagGPSLatitude))
+		longitude := exifRationals(ed.order, gps.sub.get(exifTagGPSLongitude))
+		if len(latitude) == 3 && len(longitude) == 3 {
+			lat := latitude[0] + latitude[1]/60 + latitude[2]/3600
+			lon := longitude[0] + longitude[1]/60 + longitude[2]/3600
+			if exifString(gps.sub.get(exifTagGPSLatitudeRef)) == "S" {
+				lat = -lat
+			}
+			if exifString(gps.sub.get(exifTagGPSLongitudeRef)) == "W" {
+				lon = -lon
+			}
+			result.Latitude.SetValue(lat)
+			result.Longitude.SetValue(lon)
+		}
+		if altitude := exifRationals(ed.order, gps.sub.get(exifTagGPSAltitude)); len(altitude) == 1 {
+			ele := altitude[0]
+			if ref := gps.sub.get(exifTagGPSAltitudeRef); ref != nil && len(ref.data) == 1 && ref.data[0] == 1 {
+				ele = -ele
+			}
+			result.Elevation.SetValue(ele)
+		}
+	}
+
+	return result
+}
+
+// ----------------------------------------------------------------------------------------------------
+// TIFF writing
+// ----------------------------------------------------------------------------------------------------
+
+type tiffWriter struct {
+	order binary.ByteOrder
+	buf   []byte
+}
+
+func (w *tiffWriter) align() {
+	if len(w.buf)%2 == 1 {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+func (w *tiffWriter) writeIFD(ifd *exifIFD) uint32 {
+	w.align()
+	offset := uint32(len(w.buf))
+
+	entries := append([]exifEntry{}, ifd.entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	w.buf = append(w.buf, make([]byte, 2+12*len(entries)+4)...)
+	w.order.PutUint16(w.buf[offset:], uint16(len(entries)))
+	for entryNo, entry := range entries {
+		pos := offset + 2 + 12*uint32(entryNo)
+		w.order.PutUint16(w.buf[pos:], entry.tag)
+		w.order.PutUint16(w.buf[pos+2:], entry.typ)
+		w.order.PutUint32(w.buf[pos+4:], entry.count)
+
+		switch {
+		case entry.sub != nil:
+			subOffset := w.writeIFD(entry.sub)
+			w.order.PutUint32(w.buf[pos+8:], subOffset)
+		case entry.tag == exifTagThumbnailOffset && ifd.thumbnail != nil:
+			w.align()
+			w.order.PutUint32(w.buf[pos+8:], uint32(len(w.buf)))
+			w.buf = append(w.buf, ifd.thumbnail...)
+		case len(entry.data) <= 4:
+			copy(w.buf[pos+8:pos+12], entry.data)
+		default:
+			w.align()
+			w.order.PutUint32(w.buf[pos+8:], uint32(len(w.buf)))
+			w.buf = append(w.buf, entry.data...)
+		}
+	}
+	return offset
+}
+
+func (ed *exifData) toTIFF() []byte {
+	w := &tiffWriter{order: ed.order}
+	if ed.order == binary.BigEndian {
+		w.buf = append(w.buf, 'M', 'M')
+	} else {
+		w.buf = append(w.buf, 'I', 'I')
+	}
+	w.buf = append(w.buf, make([]byte, 6)...)
+	w.order.PutUint16(w.buf[2:], 42)
+
+	ifd0Offset := w.writeIFD(ed.ifd0)
+	w.order.PutUint32(w.buf[4:], ifd0Offset)
+	if ed.ifd1 != nil {
+		ifd1Offset := w.writeIFD(ed.ifd1)
+		// The "next IFD" pointer is the last 4 bytes of IFD0:
+		nextPos := ifd0Offset + 2 + 12*uint32(len(ed.ifd0.entries))
+		w.order.PutUint32(w.buf[nextPos:], ifd1Offset)
+	}
+	return w.buf
+}
+
+func (ed *exifData) rationals(values ...float64) []byte {
+	result := make([]byte, 8*len(values))
+	for valueNo, value := range values {
+		divisor := uint32(exifDefaultRationalDivisor)
+		for divisor > 1 && value*float64(divisor) > math.MaxUint32 {
+			divisor /= 10
+		}
+		ed.order.PutUint32(result[8*valueNo:], uint32(math.Round(value*float64(divisor))))
+		ed.order.PutUint32(result[8*valueNo+4:], divisor)
+	}
+	return result
+}
+
+func (ed *exifData) setLocation(point GPXPoint) {
+	gps := &exifIFD{}
+
+	degreesMinutesSeconds := func(value float64) []byte {
+		value = math.Abs(value)
+		degrees := math.Floor(value)
+		minutes := math.Floor((value - degrees) * 60)
+		seconds := (value - degrees - minutes/60) * 3600
+		return ed.rationals(degrees, minutes, seconds)
+	}
+	ascii := func(tag uint16, value string) exifEntry {
+		return exifEntry{tag: tag, typ: exifTypeASCII, count: uint32(len(value) + 1), data: append([]byte(value), 0)}
+	}
+
+	latitudeRef, longitudeRef := "N", "E"
+	if point.Latitude < 0 {
+		latitudeRef = "S"
+	}
+	if point.Longitude < 0 {
+		longitudeRef = "W"
+	}
+
+	gps.set(exifEntry{tag: exifTagGPSVersionID, typ: exifTypeByte, count: 4, data: []byte{2, 3, 0, 0}})
+	gps.set(ascii(exifTagGPSLatitudeRef, latitudeRef))
+	gps.set(exifEntry{tag: exifTagGPSLatitude, typ: exifTypeRational, count: 3, data: degreesMinutesSeconds(point.Latitude)})
+	gps.set(ascii(exifTagGPSLongitudeRef, longitudeRef))
+	gps.set(exifEntry{tag: exifTagGPSLongitude, typ: exifTypeRational, count: 3, data: degreesMinutesSeconds(point.Longitude)})
+	if point.Elevation.NotNull() {
+		var ref byte
+		if point.Elevation.Value() < 0 {
+			ref = 1
+		}
+		gps.set(exifEntry{tag: exifTagGPSAltitudeRef, typ: exifTypeByte, count: 1, data: []byte{ref}})
+		gps.set(exifEntry{tag: exifTagGPSAltitude, typ: exifTypeRational, count: 1, data: ed.rationals(math.Abs(point.Elevation.Value()))})
+	}
+	if hasTimestamp(&point) {
+		t := point.Timestamp.UTC()
+		seconds := float64(t.Second()) + float64(t.Nanosecond())/1e9
+		gps.set(exifEntry{tag: exifTagGPSTimeStamp, typ: exifTypeRational, count: 3, data: ed.rationals(float64(t.Hour()), float64(t.Minute()), seconds)})
+		gps.set(ascii(exifTagGPSDateStamp, t.Format("2006:01:02")))
+	}
+
+	pointer := make([]byte, 4)
+	ed.ifd0.set(exifEntry{tag: exifTagGPSIFD, typ: exifTypeLong, count: 1, data: pointer, sub: gps})
+}
+
+// ----------------------------------------------------------------------------------------------------
+// JPEG
+// ----------------------------------------------------------------------------------------------------
+
+type jpegSegment struct {
+	marker byte
+	data   []byte
+}
+
+// splitJPEG returns the segments before the image data and the rest of the file (starting with SOS)
+func splitJPEG(jpeg []byte) ([]jpegSegment, []byte, error) {
+	if len(jpeg) < 4 || jpeg[0] != 0xFF || jpeg[1] != jpegMarkerSOI {
+		return nil, nil, errors.New("not a JPEG file")
+	}
+	segments := make([]jpegSegment, 0)
+	pos := 2
+	for pos+4 <= len(jpeg) {
+		if jpeg[pos] != 0xFF {
+			return nil, nil, fmt.Errorf("invalid JPEG marker at %d", pos)
+		}
+		marker := jpeg[pos+1]
+		if marker == 0xFF {
+			// Fill byte
+			pos++
+			continue
+		}
+		if marker == jpegMarkerSOS || marker == jpegMarkerEOI {
+			return segments, jpeg[pos:], nil
+		}
+		length := int(binary.BigEndian.Uint16(jpeg[pos+2:]))
+		if length < 2 || pos+2+length > len(jpeg) {
+			return nil, nil, errors.New("invalid JPEG segment length")
+		}
+		segments = append(segments, jpegSegment{marker: marker, data: jpeg[pos+4 : pos+2+length]})
+		pos += 2 + length
+	}
+	return nil, nil, errors.New("JPEG image data not found")
+}
+
+func isExifSegment(segment jpegSegment) bool {
+	return segment.marker == jpegMarkerAPP1 && bytes.HasPrefix(segment.data, []byte(exifHeader))
+}
+
+// ReadJPEGExif reads the EXIF time and GPS data from JPEG bytes
+func ReadJPEGExif(jpeg []byte) (*PhotoExif, error) {
+	segments, _, err := splitJPEG(jpeg)
+	if err != nil {
+		return nil, err
+	}
+	for _, segment := range segments {
+		if isExifSegment(segment) {
+			data, err := parseTIFF(segment.data[len(exifHeader):])
+			if err != nil {
+				return nil, err
+			}
+			return data.photoExif(), nil
+		}
+	}
+	return new(PhotoExif), nil
+}
+
+// ReadJPEGExifFile reads the EXIF time and GPS data from a JPEG file
+func ReadJPEGExifFile(fileName string) (*PhotoExif, error) {
+	jpeg, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return ReadJPEGExif(jpeg)
+}
+
+// WriteJPEGLocation returns the JPEG with the GPS location (latitude,
+// longitude, elevation and time) of the point written into its EXIF data.
+// Existing EXIF tags are preserved, but vendor specific maker notes with
+// absolute offsets may become invalid.
+func WriteJPEGLocation(jpeg []byte, point GPXPoint) ([]byte, error) {
+	segments, imageData, err := splitJPEG(jpeg)
+	if err != nil {
+		return nil, err
+	}
+
+	exifNo := -1
+	data := &exifData{order: binary.LittleEndian, ifd0: &exifIFD{}}
+	for segmentNo, segment := range segments {
+		if isExifSegment(segment) {
+			data, err = parseTIFF(segment.data[len(exifHeader):])
+			if err != nil {
+				return nil, err
+			}
+			exifNo = segmentNo
+			break
+		}
+	}
+
+	data.ifd0.remove(exifTagGPSIFD)
+	data.setLocation(point)
+	tiff := data.toTIFF()
+	if len(exifHeader)+len(tiff) > exifMaxSegmentLength && data.ifd1 != nil {
+		// Drop the thumbnail if the segment is too big:
+		data.ifd1 = nil
+		tiff = data.toTIFF()
+	}
+	if len(exifHeader)+len(tiff) > exifMaxSegmentLength {
+		return nil, errors.New("EXIF data too big")
+	}
+	exifSegment := jpegSegment{marker: jpegMarkerAPP1, data: append([]byte(exifHeader), tiff...)}
+
+	if exifNo >= 0 {
+		segments[exifNo] = exifSegment
+	} else {
+		// After the JFIF header (if any):
+		insertAt := 0
+		if len(segments) > 0 && segments[0].marker == jpegMarkerAPP0 {
+			insertAt = 1
+		}
+		segments = append(segments[:insertAt], append([]jpegSegment{exifSegment}, segments[insertAt:]...)...)
+	}
+
+	var result bytes.Buffer
+	result.Write([]byte{0xFF, jpegMarkerSOI})
+	for _, segment := range segments {
+		length := make([]byte, 2)
+		binary.BigEndian.PutUint16(length, uint16(len(segment.data)+2))
+		result.Write([]byte{0xFF, segment.marker})
+		result.Write(length)
+		result.Write(segment.data)
+	}
+	result.Write(imageData)
+	return result.Bytes(), nil
+}
+
+// WriteJPEGLocationFile writes the GPS location of the point into the EXIF data of a JPEG file
+func WriteJPEGLocationFile(fileName string, point GPXPoint) error {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return err
+	}
+	jpeg, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	result, err := WriteJPEGLocation(jpeg, point)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, result, info.Mode())
+}
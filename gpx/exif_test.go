@@ -0,0 +1,122 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestJPEG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 16, 16)), nil)
+	assert.Nil(t, err)
+	return buf.Bytes()
+}
+
+// newTestJPEGWithExif creates a JPEG with a big endian EXIF segment containing DateTimeOriginal and a thumbnail
+func newTestJPEGWithExif(t *testing.T, dateTimeOriginal string) []byte {
+	data := &exifData{order: binary.BigEndian, ifd0: &exifIFD{}}
+	exifSub := &exifIFD{}
+	exifSub.set(exifEntry{tag: exifTagDateTimeOriginal, typ: exifTypeASCII, count: uint32(len(dateTimeOriginal) + 1), data: append([]byte(dateTimeOriginal), 0)})
+	data.ifd0.set(exifEntry{tag: exifTagExifIFD, typ: exifTypeLong, count: 1, data: make([]byte, 4), sub: exifSub})
+	data.ifd0.set(exifEntry{tag: 0x010F, typ: exifTypeASCII, count: 6, data: []byte("Maker\x00")})
+
+	thumbnail := []byte{0xFF, 0xD8, 1, 2, 3, 0xFF, 0xD9}
+	data.ifd1 = &exifIFD{thumbnail: thumbnail}
+	data.ifd1.set(exifEntry{tag: exifTagThumbnailOffset, typ: exifTypeLong, count: 1, data: make([]byte, 4)})
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(thumbnail)))
+	data.ifd1.set(exifEntry{tag: exifTagThumbnailLength, typ: exifTypeLong, count: 1, data: length})
+
+	app1 := append([]byte(exifHeader), data.toTIFF()...)
+	header := []byte{0xFF, jpegMarkerSOI, 0xFF, jpegMarkerAPP1, 0, 0}
+	binary.BigEndian.PutUint16(header[4:], uint16(len(app1)+2))
+
+	original := newTestJPEG(t)
+	return append(append(header, app1...), original[2:]...)
+}
+
+func TestWriteAndReadJPEGLocation(t *testing.T) {
+	t.Parallel()
+
+	original := newTestJPEG(t)
+	exif, err := ReadJPEGExif(original)
+	assert.Nil(t, err)
+	assert.True(t, exif.Latitude.Null())
+	assert.True(t, exif.Time.Null())
+
+	var point GPXPoint
+	point.Latitude = 45.123456
+	point.Longitude = -13.654321
+	point.Elevation.SetValue(-12.5)
+	point.Timestamp = time.Date(2020, 5, 1, 10, 11, 12, 0, time.UTC)
+
+	geotagged, err := WriteJPEGLocation(original, point)
+	assert.Nil(t, err)
+	_, err = jpeg.Decode(bytes.NewReader(geotagged))
+	assert.Nil(t, err)
+
+	exif, err = ReadJPEGExif(geotagged)
+	assert.Nil(t, err)
+	assert.InDelta(t, 45.123456, exif.Latitude.Value(), 1e-7)
+	assert.InDelta(t, -13.654321, exif.Longitude.Value(), 1e-7)
+	assert.InDelta(t, -12.5, exif.Elevation.Value(), 1e-7)
+
+	// Writing again replaces the location:
+	point.Latitude = -1.5
+	geotagged, err = WriteJPEGLocation(geotagged, point)
+	assert.Nil(t, err)
+	exif, err = ReadJPEGExif(geotagged)
+	assert.Nil(t, err)
+	assert.InDelta(t, -1.5, exif.Latitude.Value(), 1e-7)
+}
+
+func TestWriteJPEGLocationPreservesExif(t *testing.T) {
+	t.Parallel()
+
+	original := newTestJPEGWithExif(t, "2020:05:01 12:00:00")
+	exif, err := ReadJPEGExif(original)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC), exif.Time.Value())
+	assert.False(t, exif.HasTimeZone)
+
+	var point GPXPoint
+	point.Latitude = 46.5
+	point.Longitude = 14.25
+	geotagged, err := WriteJPEGLocation(original, point)
+	assert.Nil(t, err)
+
+	exif, err = ReadJPEGExif(geotagged)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC), exif.Time.Value())
+	assert.InDelta(t, 46.5, exif.Latitude.Value(), 1e-7)
+	assert.InDelta(t, 14.25, exif.Longitude.Value(), 1e-7)
+	assert.True(t, exif.Elevation.Null())
+
+	segments, _, err := splitJPEG(geotagged)
+	assert.Nil(t, err)
+	data, err := parseTIFF(segments[0].data[len(exifHeader):])
+	assert.Nil(t, err)
+	assert.Equal(t, binary.BigEndian, data.order)
+	assert.Equal(t, "Maker", exifString(data.ifd0.get(0x010F)))
+	assert.Equal(t, []byte{0xFF, 0xD8, 1, 2, 3, 0xFF, 0xD9}, data.ifd1.thumbnail)
+}
+
+func TestReadInvalidJPEG(t *testing.T) {
+	t.Parallel()
+
+	_, err := ReadJPEGExif([]byte("not a jpeg"))
+	assert.NotNil(t, err)
+	_, err = WriteJPEGLocation([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF}, GPXPoint{})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,65 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"time"
+)
+
+// isGap returns true if the time between the points is longer than
+// maxTimeGap or the 2D distance is bigger than maxDistanceJump (zero values
+// are ignored)
+func isGap(point1, point2 *GPXPoint, maxTimeGap time.Duration, maxDistanceJump float64) bool {
+	if maxTimeGap > 0 && hasTimestamp(point1) && hasTimestamp(point2) && point2.Timestamp.Sub(point1.Timestamp) > maxTimeGap {
+		return true
+	}
+	return maxDistanceJump > 0 && point2.Distance2D(point1) > maxDistanceJump
+}
+
+// SplitOnGaps returns the parts of the segment between time gaps longer
+// than maxTimeGap and jumps longer than maxDistanceJump meters (zero
+// disables the check). The segment is returned unchanged (as the only
+// part) if there are no gaps. The parts have copies of the points.
+func (seg *GPXTrackSegment) SplitOnGaps(maxTimeGap time.Duration, maxDistanceJump float64) []GPXTrackSegment {
+	result := make([]GPXTrackSegment, 0)
+	part := func(from, to int) GPXTrackSegment {
+		points := make([]GPXPoint, to-from)
+		copy(points, seg.Points[from:to])
+		return GPXTrackSegment{Points: points, Extensions: seg.Extensions}
+	}
+	first := 0
+	for pointNo := 1; pointNo < len(seg.Points); pointNo++ {
+		if isGap(&seg.Points[pointNo-1], &seg.Points[pointNo], maxTimeGap, maxDistanceJump) {
+			result = append(result, part(first, pointNo))
+			first = pointNo
+		}
+	}
+	return append(result, part(first, len(seg.Points)))
+}
+
+// SplitOnGaps splits the track segments on time gaps and distance jumps and
+// returns the number of splits. Use Join/JoinNext to join segments.
+func (trk *GPXTrack) SplitOnGaps(maxTimeGap time.Duration, maxDistanceJump float64) int {
+	var splits int
+	segments := make([]GPXTrackSegment, 0, len(trk.Segments))
+	for segmentNo := range trk.Segments {
+		parts := trk.Segments[segmentNo].SplitOnGaps(maxTimeGap, maxDistanceJump)
+		splits += len(parts) - 1
+		segments = append(segments, parts...)
+	}
+	trk.Segments = segments
+	return splits
+}
+
+// SplitOnGaps splits the segments of all tracks on time gaps and distance
+// jumps and returns the number of splits
+func (g *GPX) SplitOnGaps(maxTimeGap time.Duration, maxDistanceJump float64) int {
+	var splits int
+	for trackNo := range g.Tracks {
+		splits += g.Tracks[trackNo].SplitOnGaps(maxTimeGap, maxDistanceJump)
+	}
+	return splits
+}
@@ -0,0 +1,65 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentSplitOnGaps(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 100, 5000, 100, 100)
+	seg.Points[5].Timestamp = seg.Points[5].Timestamp.Add(time.Hour)
+
+	parts := seg.SplitOnGaps(0, 1000)
+	assert.Equal(t, 2, len(parts))
+	assert.Equal(t, seg.Points[:3], parts[0].Points)
+	assert.Equal(t, seg.Points[3:], parts[1].Points)
+
+	parts = seg.SplitOnGaps(time.Minute, 0)
+	assert.Equal(t, 2, len(parts))
+	assert.Equal(t, 5, len(parts[0].Points))
+	assert.Equal(t, 1, len(parts[1].Points))
+
+	assert.Equal(t, 3, len(seg.SplitOnGaps(time.Minute, 1000)))
+	assert.Equal(t, 1, len(seg.SplitOnGaps(0, 0)))
+	assert.Equal(t, 1, len((&GPXTrackSegment{}).SplitOnGaps(time.Minute, 1000)))
+
+	// Changing the parts doesn't change the segment:
+	original := append([]GPXPoint{}, seg.Points...)
+	parts = seg.SplitOnGaps(0, 1000)
+	parts[0].Points = append(parts[0].Points, seg.Points[0])
+	parts[1].Points = append(parts[1].Points, seg.Points[0])
+	parts[1].Points[0].Latitude = 0
+	assert.Equal(t, original, seg.Points)
+}
+
+func TestSplitOnGapsAndJoin(t *testing.T) {
+	t.Parallel()
+
+	g := GPX{Tracks: []GPXTrack{
+		{Segments: []GPXTrackSegment{newTestSegment(10, 100, 5000, 100), newTestSegment(10, 100)}},
+		{Segments: []GPXTrackSegment{newTestSegment(10, 5000, 5000)}},
+	}}
+	pointsNo := g.GetTrackPointsNo()
+
+	assert.Equal(t, 3, g.SplitOnGaps(time.Minute, 1000))
+	assert.Equal(t, 3, len(g.Tracks[0].Segments))
+	assert.Equal(t, 3, len(g.Tracks[1].Segments))
+	assert.Equal(t, pointsNo, g.GetTrackPointsNo())
+	assert.Equal(t, 0, g.SplitOnGaps(time.Minute, 1000))
+
+	second := append([]GPXPoint{}, g.Tracks[0].Segments[1].Points...)
+	g.Tracks[0].Join(0, 2)
+	assert.Equal(t, 2, len(g.Tracks[0].Segments))
+	assert.Equal(t, 4, len(g.Tracks[0].Segments[0].Points))
+	// Joining doesn't overwrite the other parts:
+	assert.Equal(t, second, g.Tracks[0].Segments[1].Points)
+}
@@ -93,6 +93,15 @@ func Length3D(locs []Point) float64 {
 	return length(locs, true)
 }
 
+// cumulativeDistances returns the 2D distance from the first point for every point
+func cumulativeDistances(points []GPXPoint) []float64 {
+	result := make([]float64, len(points))
+	for pointNo := 1; pointNo < len(points); pointNo++ {
+		result[pointNo] = result[pointNo-1] + points[pointNo].Distance2D(&points[pointNo-1])
+	}
+	return result
+}
+
 //CalcMaxSpeed returns the maximum speed
 func CalcMaxSpeed(speedsDistances []SpeedsAndDistances) float64 {
 	lenArrs := len(speedsDistances)
@@ -292,6 +301,42 @@ func distanceFromLine(point Point, linePoint1, linePoint2 GPXPoint) float64 {
 	return 2.0 * math.Sqrt(math.Abs(s*(s-a)*(s-b)*(s-c))) / a
 }
 
+// distanceFromSegment returns the distance of the point from the line
+// segment between two points and the distance along it (from the first)
+// of the nearest location.
+func distanceFromSegment(point Point, linePoint1, linePoint2 GPXPoint) (distance, along float64) {
+	a := linePoint1.Distance2D(&linePoint2)
+	b := linePoint1.Distance2D(&point)
+	if a == 0 {
+		return b, 0
+	}
+	c := linePoint2.Distance2D(&point)
+	along = (a*a + b*b - c*c) / (2 * a)
+	if along <= 0 {
+		return b, 0
+	}
+	if along >= a {
+		return c, a
+	}
+	return distanceFromLine(point, linePoint1, linePoint2), along
+}
+
+// nearestOnPolyline returns the distance of the point from the polyline and
+// the distance along the polyline (see cumulativeDistances) of the nearest
+// location
+func nearestOnPolyline(points []GPXPoint, distances []float64, point Point) (distance, along float64) {
+	distance = math.Inf(1)
+	if len(points) == 1 {
+		return points[0].Distance2D(&point), 0
+	}
+	for pointNo := 1; pointNo < len(points); pointNo++ {
+		if d, a := distanceFromSegment(point, points[pointNo-1], points[pointNo]); d < distance {
+			distance, along = d, distances[pointNo-1]+a
+		}
+	}
+	return distance, along
+}
+
 func getLineEquationCoefficients(location1, location2 Point) (float64, float64, float64) {
 	if location1.Longitude == location2.Longitude {
 		// Vertical line:
@@ -0,0 +1,173 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+)
+
+// GeoidModel computes the geoid undulation (the height of the geoid above
+// the WGS84 ellipsoid) in meters for a given location.
+type GeoidModel interface {
+	GeoidHeight(latitude, longitude float64) float64
+}
+
+// GeoidGrid is a GeoidModel with undulations sampled on a regular
+// latitude/longitude grid (for example the EGM96 15' grid WW15MGH.GRD).
+type GeoidGrid struct {
+	MinLatitude   float64
+	MaxLatitude   float64
+	MinLongitude  float64
+	MaxLongitude  float64
+	LatitudeStep  float64
+	LongitudeStep float64
+
+	rows    int
+	columns int
+	// Rows are stored from north (MaxLatitude) to south, columns from west to east:
+	heights []float64
+}
+
+var _ GeoidModel = (*GeoidGrid)(nil)
+
+// ParseGeoidGridFile loads a geoid grid file in the NGA "WW15MGH.GRD" format.
+func ParseGeoidGridFile(fileName string) (*GeoidGrid, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseGeoidGrid(f)
+}
+
+// ParseGeoidGrid parses a geoid grid in the NGA "WW15MGH.GRD" format. The
+// first six numbers are the south, north, west and east bounds and the
+// latitude and longitude spacing (all in degrees), followed by the
+// undulations row by row from north to south, every row from west to east.
+func ParseGeoidGrid(reader io.Reader) (*GeoidGrid, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Split(bufio.ScanWords)
+
+	values := make([]float64, 0)
+	for scanner.Scan() {
+		value, err := strconv.ParseFloat(scanner.Text(), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid geoid grid value %s: %s", scanner.Text(), err.Error())
+		}
+		values = append(values, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(values) < 6 {
+		return nil, errors.New("invalid geoid grid, missing header")
+	}
+
+	grid := &GeoidGrid{
+		MinLatitude:   values[0],
+		MaxLatitude:   values[1],
+		MinLongitude:  values[2],
+		MaxLongitude:  values[3],
+		LatitudeStep:  values[4],
+		LongitudeStep: values[5],
+	}
+	if grid.LatitudeStep <= 0 || grid.LongitudeStep <= 0 || grid.MaxLatitude <= grid.MinLatitude || grid.MaxLongitude <= grid.MinLongitude {
+		return nil, errors.New("invalid geoid grid header")
+	}
+
+	grid.rows = int(math.Round((grid.MaxLatitude-grid.MinLatitude)/grid.LatitudeStep)) + 1
+	grid.columns = int(math.Round((grid.MaxLongitude-grid.MinLongitude)/grid.LongitudeStep)) + 1
+	grid.heights = values[6:]
+
+	if len(grid.heights) != grid.rows*grid.columns {
+		return nil, fmt.Errorf("invalid geoid grid, expected %d values, found %d", grid.rows*grid.columns, len(grid.heights))
+	}
+
+	return grid, nil
+}
+
+func (g *GeoidGrid) height(row, column int) float64 {
+	if row < 0 {
+		row = 0
+	}
+	if row >= g.rows {
+		row = g.rows - 1
+	}
+	if column >= g.columns {
+		column = g.columns - 1
+	}
+	return g.heights[row*g.columns+column]
+}
+
+// GeoidHeight returns the bilinearly interpolated geoid undulation in meters.
+func (g *GeoidGrid) GeoidHeight(latitude, longitude float64) float64 {
+	latitude = math.Max(g.MinLatitude, math.Min(g.MaxLatitude, latitude))
+
+	lonRange := g.MaxLongitude - g.MinLongitude
+	for longitude < g.MinLongitude {
+		longitude += 360
+	}
+	for longitude > g.MaxLongitude && longitude-360 >= g.MinLongitude {
+		longitude -= 360
+	}
+	longitude = math.Max(g.MinLongitude, math.Min(g.MinLongitude+lonRange, longitude))
+
+	y := (g.MaxLatitude - latitude) / g.LatitudeStep
+	x := (longitude - g.MinLongitude) / g.LongitudeStep
+
+	row, column := int(math.Floor(y)), int(math.Floor(x))
+	dy, dx := y-float64(row), x-float64(column)
+
+	h00 := g.height(row, column)
+	h01 := g.height(row, column+1)
+	h10 := g.height(row+1, column)
+	h11 := g.height(row+1, column+1)
+
+	return h00*(1-dx)*(1-dy) + h01*dx*(1-dy) + h10*(1-dx)*dy + h11*dx*dy
+}
+
+// ----------------------------------------------------------------------------------------------------
+
+// FillGeoidHeights sets the geoid height of all points
+func (g *GPX) FillGeoidHeights(geoid GeoidModel) {
+	g.ExecuteOnAllPoints(func(point *GPXPoint) {
+		point.GeoidHeight.SetValue(geoid.GeoidHeight(point.Latitude, point.Longitude))
+	})
+}
+
+// ToOrthometricHeights converts all elevations from heights above the WGS84
+// ellipsoid (as recorded by most GPS receivers) to heights above mean sea
+// level. The geoid height of every point is filled.
+func (g *GPX) ToOrthometricHeights(geoid GeoidModel) {
+	g.ExecuteOnAllPoints(func(point *GPXPoint) {
+		undulation := geoid.GeoidHeight(point.Latitude, point.Longitude)
+		point.GeoidHeight.SetValue(undulation)
+		if point.Elevation.NotNull() {
+			point.Elevation.SetValue(point.Elevation.Value() - undulation)
+		}
+	})
+}
+
+// ToEllipsoidalHeights converts all elevations from heights above mean sea
+// level to heights above the WGS84 ellipsoid. The geoid height of every
+// point is filled.
+func (g *GPX) ToEllipsoidalHeights(geoid GeoidModel) {
+	g.ExecuteOnAllPoints(func(point *GPXPoint) {
+		undulation := geoid.GeoidHeight(point.Latitude, point.Longitude)
+		point.GeoidHeight.SetValue(undulation)
+		if point.Elevation.NotNull() {
+			point.Elevation.SetValue(point.Elevation.Value() + undulation)
+		}
+	})
+}
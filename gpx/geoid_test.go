@@ -0,0 +1,68 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadTestGeoidGrid(t *testing.T) *GeoidGrid {
+	grid, err := ParseGeoidGridFile("../test_files/geoid_test_grid.grd")
+	assert.Nil(t, err)
+	return grid
+}
+
+func TestParseGeoidGrid(t *testing.T) {
+	t.Parallel()
+
+	grid := loadTestGeoidGrid(t)
+	assert.Equal(t, 90.0, grid.LatitudeStep)
+	assert.Equal(t, 10.0, grid.GeoidHeight(90, 0))
+	assert.Equal(t, 40.0, grid.GeoidHeight(0, 180))
+	assert.Equal(t, 15.0, grid.GeoidHeight(45, 90))
+	assert.Equal(t, 30.0, grid.GeoidHeight(0, 135))
+	// Negative longitudes wrap around:
+	assert.Equal(t, 20.0, grid.GeoidHeight(0, -90))
+	assert.Equal(t, -10.0, grid.GeoidHeight(-90, 10))
+}
+
+func TestParseInvalidGeoidGrid(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseGeoidGrid(strings.NewReader("-90 90 0 360 90 90\n1 2 3"))
+	assert.NotNil(t, err)
+	_, err = ParseGeoidGrid(strings.NewReader("-90 90 0"))
+	assert.NotNil(t, err)
+	_, err = ParseGeoidGrid(strings.NewReader("-90 90 0 360 90 a"))
+	assert.NotNil(t, err)
+}
+
+func TestOrthometricAndEllipsoidalHeights(t *testing.T) {
+	t.Parallel()
+
+	grid := loadTestGeoidGrid(t)
+
+	g := GPX{}
+	g.AppendPoint(&GPXPoint{Point: Point{Latitude: 0, Longitude: 180, Elevation: *NewNullableFloat64(100)}})
+	g.AppendPoint(&GPXPoint{Point: Point{Latitude: 45, Longitude: 90}})
+
+	g.ToOrthometricHeights(grid)
+	points := g.Tracks[0].Segments[0].Points
+	assert.Equal(t, 60.0, points[0].Elevation.Value())
+	assert.Equal(t, 40.0, points[0].GeoidHeight.Value())
+	assert.True(t, points[1].Elevation.Null())
+	assert.Equal(t, 15.0, points[1].GeoidHeight.Value())
+
+	g.ToEllipsoidalHeights(grid)
+	assert.Equal(t, 100.0, g.Tracks[0].Segments[0].Points[0].Elevation.Value())
+
+	xml, err := g.ToXml(ToXmlParams{Version: "1.1"})
+	assert.Nil(t, err)
+	assert.Contains(t, string(xml), "<geoidheight>40</geoidheight>")
+}
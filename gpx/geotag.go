@@ -0,0 +1,117 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"time"
+)
+
+// GeotagOptions contains settings for matching photo times with track times
+type GeotagOptions struct {
+	// CameraOffset is added to every photo time (use it when the camera clock is wrong)
+	CameraOffset time.Duration
+	// CameraLocation, if set, is the time zone of the camera clock. The wall
+	// clock of every photo time is interpreted in this time zone (cameras
+	// usually store local times without any zone information).
+	CameraLocation *time.Location
+	// MaxGap is the maximum time between the photo and the nearest track
+	// point. Photos taken up to MaxGap before the start or after the end of a
+	// segment are placed at its first/last point. Zero means that photos must
+	// be taken within the segment time bounds.
+	MaxGap time.Duration
+}
+
+// PhotoLocation is the result of matching one photo time with a track
+type PhotoLocation struct {
+	// Time is the (corrected) photo time
+	Time     time.Time
+	Found    bool
+	Location InterpolatedLocation
+}
+
+// ToWaypoint creates a waypoint for the photo
+func (pl PhotoLocation) ToWaypoint(name string) GPXPoint {
+	var result GPXPoint
+	result.Point = pl.Location.Point
+	result.Timestamp = pl.Time.UTC()
+	result.Name = name
+	return result
+}
+
+func (opts GeotagOptions) correctTime(t time.Time) time.Time {
+	if opts.CameraLocation != nil {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), opts.CameraLocation)
+	}
+	return t.Add(opts.CameraOffset)
+}
+
+// GeotagTime finds the location for a single photo time
+func (g *GPX) GeotagTime(t time.Time, opts GeotagOptions) PhotoLocation {
+	return g.GeotagTimes([]time.Time{t}, opts)[0]
+}
+
+// GeotagTimes finds the (interpolated) locations for the given photo times
+func (g *GPX) GeotagTimes(times []time.Time, opts GeotagOptions) []PhotoLocation {
+	result := make([]PhotoLocation, len(times))
+	for timeNo, t := range times {
+		t = opts.correctTime(t)
+		result[timeNo] = PhotoLocation{Time: t}
+		if location, found := g.geotagTime(t, opts.MaxGap); found {
+			result[timeNo].Location = location
+			result[timeNo].Found = true
+		}
+	}
+	return result
+}
+
+func (g *GPX) geotagTime(t time.Time, maxGap time.Duration) (location InterpolatedLocation, found bool) {
+	var nearestEnd *InterpolatedLocation
+	var nearestEndGap time.Duration
+
+	for trackNo := range g.Tracks {
+		for segmentNo := range g.Tracks[trackNo].Segments {
+			seg := &g.Tracks[trackNo].Segments[segmentNo]
+			if len(seg.Points) == 0 || !hasTimestamp(&seg.Points[0]) {
+				continue
+			}
+			if loc, ok := seg.LocationAtTime(t); ok {
+				if maxGap > 0 && loc.PointNo < len(seg.Points)-1 {
+					before := t.Sub(seg.Points[loc.PointNo].Timestamp)
+					after := seg.Points[loc.PointNo+1].Timestamp.Sub(t)
+					if before > maxGap && after > maxGap {
+						continue
+					}
+				}
+				loc.TrackNo, loc.SegmentNo = trackNo, segmentNo
+				return loc, true
+			}
+			if maxGap <= 0 {
+				continue
+			}
+
+			lastNo := len(seg.Points) - 1
+			candidates := []struct {
+				pointNo int
+				gap     time.Duration
+			}{
+				{pointNo: 0, gap: seg.Points[0].Timestamp.Sub(t)},
+				{pointNo: lastNo, gap: t.Sub(seg.Points[lastNo].Timestamp)},
+			}
+			for _, candidate := range candidates {
+				if 0 <= candidate.gap && candidate.gap <= maxGap && (nearestEnd == nil || candidate.gap < nearestEndGap) {
+					loc := newInterpolatedLocation(seg, candidate.pointNo, 0)
+					loc.TrackNo, loc.SegmentNo = trackNo, segmentNo
+					nearestEnd, nearestEndGap = &loc, candidate.gap
+				}
+			}
+		}
+	}
+
+	if nearestEnd != nil {
+		return *nearestEnd, true
+	}
+	return
+}
@@ -0,0 +1,68 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeotagTimes(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 100, 100)
+	g := GPX{}
+	g.AppendSegment(&seg)
+
+	locations := g.GeotagTimes([]time.Time{
+		testStartTime.Add(5 * time.Second),
+		testStartTime.Add(-time.Minute),
+		testStartTime.Add(35 * time.Second),
+	}, GeotagOptions{MaxGap: 10 * time.Second})
+
+	assert.Equal(t, 3, len(locations))
+	assert.True(t, locations[0].Found)
+	assert.InDelta(t, 50, locations[0].Location.Distance2D(&seg.Points[0]), 0.01)
+	assert.False(t, locations[1].Found)
+	assert.True(t, locations[2].Found)
+	assert.Equal(t, seg.Points[3].Latitude, locations[2].Location.Latitude)
+
+	waypoint := locations[0].ToWaypoint("IMG_0001.JPG")
+	assert.Equal(t, "IMG_0001.JPG", waypoint.Name)
+	assert.Equal(t, testStartTime.Add(5*time.Second), waypoint.Timestamp)
+}
+
+func TestGeotagWithCameraOffsetAndTimezone(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 100, 100)
+	g := GPX{}
+	g.AppendSegment(&seg)
+
+	// The camera is in UTC+2 and its clock is 1 minute late:
+	cameraTime := time.Date(2020, 5, 1, 11, 59, 5, 0, time.UTC)
+	location := g.GeotagTime(cameraTime, GeotagOptions{
+		CameraOffset:   time.Minute,
+		CameraLocation: time.FixedZone("UTC+2", 2*60*60),
+	})
+	assert.True(t, location.Found)
+	assert.True(t, location.Time.Equal(testStartTime.Add(5*time.Second)))
+	assert.InDelta(t, 50, location.Location.Distance2D(&seg.Points[0]), 0.01)
+}
+
+func TestGeotagMaxGapInsideSegment(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(600, 100)
+	g := GPX{}
+	g.AppendSegment(&seg)
+
+	assert.True(t, g.GeotagTime(testStartTime.Add(5*time.Minute), GeotagOptions{}).Found)
+	assert.False(t, g.GeotagTime(testStartTime.Add(5*time.Minute), GeotagOptions{MaxGap: time.Minute}).Found)
+	assert.True(t, g.GeotagTime(testStartTime.Add(9*time.Minute+30*time.Second), GeotagOptions{MaxGap: time.Minute}).Found)
+}
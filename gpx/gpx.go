@@ -13,7 +13,7 @@ import (
 
 // Global thresholds
 const (
-	defaultStoppedSpeedThreshold = 1.0
+	defaultStoppedSpeedThreshold = 1.0 // km/h
 	removeExtreemesTreshold      = 10
 )
 
@@ -198,6 +198,11 @@ func (g *GPX) ElevationBounds() ElevationBounds {
 
 // MovingData returns the moving data for all tracks in a Gpx.
 func (g *GPX) MovingData() MovingData {
+	return g.MovingDataWithOptions(DefaultMovingOptions)
+}
+
+// MovingDataWithOptions returns the moving data for all tracks in a Gpx.
+func (g *GPX) MovingDataWithOptions(opts MovingOptions) MovingData {
 	var (
 		movingTime      float64
 		stoppedTime     float64
@@ -207,7 +212,7 @@ func (g *GPX) MovingData() MovingData {
 	)
 
 	for _, trk := range g.Tracks {
-		md := trk.MovingData()
+		md := trk.MovingDataWithOptions(opts)
 		movingTime += md.MovingTime
 		stoppedTime += md.StoppedTime
 		movingDistance += md.MovingDistance
@@ -276,6 +281,16 @@ func (g *GPX) Duration() float64 {
 	return result
 }
 
+// DurationWithOptions returns the duration of all tracks in a Gpx in
+// seconds without the (auto paused) stops.
+func (g *GPX) DurationWithOptions(opts MovingOptions) float64 {
+	var result float64
+	for _, trk := range g.Tracks {
+		result += trk.DurationWithOptions(opts)
+	}
+	return result
+}
+
 // UphillDownhill returns uphill and downhill values for all tracks in a
 // Gpx.
 func (g *GPX) UphillDownhill() UphillDownhill {
@@ -332,9 +347,14 @@ func (g *GPX) PositionAt(t time.Time) []TrackPosition {
 
 // StoppedPositions returns the positions where there was a stop
 func (g *GPX) StoppedPositions() []TrackPosition {
+	return g.StoppedPositionsWithOptions(DefaultMovingOptions)
+}
+
+// StoppedPositionsWithOptions returns the positions where there was a stop
+func (g *GPX) StoppedPositionsWithOptions(opts MovingOptions) []TrackPosition {
 	result := make([]TrackPosition, 0)
 	for trackNo, track := range g.Tracks {
-		positions := track.StoppedPositions()
+		positions := track.StoppedPositionsWithOptions(opts)
 		for _, position := range positions {
 			position.TrackNo = trackNo
 			result = append(result, position)
@@ -745,10 +765,10 @@ type GPXPoint struct {
 	Point
 	// TODO
 	Timestamp time.Time
-	// TODO: Type
-	MagneticVariation string
-	// TODO: Type
-	GeoidHeight string
+	// Magnetic variation in degrees (0 <= value < 360)
+	MagneticVariation NullableFloat64
+	// Height of the geoid (mean sea level) above the WGS84 ellipsoid in meters
+	GeoidHeight NullableFloat64
 	// Description info
 	Name        string
 	Comment     string
@@ -954,7 +974,9 @@ func (seg *GPXTrackSegment) HasTimes() bool {
 	*/
 }
 
-// Speed returns the speed at point number in a GPX segment.
+// Speed returns the speed at point number in a GPX segment, the average of
+// the speeds from the previous and to the next point. Neighbours without
+// timestamps (or with the same time) are ignored, 0 if there are none.
 func (seg *GPXTrackSegment) Speed(pointIdx int) float64 {
 	trkptsLen := len(seg.Points)
 	if pointIdx >= trkptsLen {
@@ -962,44 +984,26 @@ func (seg *GPXTrackSegment) Speed(pointIdx int) float64 {
 	}
 
 	point := seg.Points[pointIdx]
-
-	var prevPt *GPXPoint
-	var nextPt *GPXPoint
-
-	havePrev := false
-	haveNext := false
-	if 0 < pointIdx && pointIdx < trkptsLen {
-		prevPt = &seg.Points[pointIdx-1]
-		havePrev = true
-	}
-
-	if 0 < pointIdx && pointIdx < trkptsLen-1 {
-		nextPt = &seg.Points[pointIdx+1]
-		haveNext = true
-	}
-
-	haveSpeed1 := false
-	haveSpeed2 := false
-
-	var speed1 float64
-	var speed2 float64
-	if havePrev {
-		speed1 = math.Abs(point.SpeedBetween(prevPt, true))
-		haveSpeed1 = true
-	}
-	if haveNext {
-		speed2 = math.Abs(point.SpeedBetween(nextPt, true))
-		haveSpeed2 = true
+	if !hasTimestamp(&point) {
+		return 0
 	}
 
-	if haveSpeed1 && haveSpeed2 {
-		return (speed1 + speed2) / 2.0
+	var sum float64
+	var count int
+	for _, neighbourIdx := range []int{pointIdx - 1, pointIdx + 1} {
+		if neighbourIdx < 0 || neighbourIdx >= trkptsLen || !hasTimestamp(&seg.Points[neighbourIdx]) {
+			continue
+		}
+		if point.TimeDiff(&seg.Points[neighbourIdx]) > 0 {
+			sum += math.Abs(point.SpeedBetween(&seg.Points[neighbourIdx], true))
+			count++
+		}
 	}
 
-	if haveSpeed1 {
-		return speed1
+	if count == 0 {
+		return 0
 	}
-	return speed2
+	return sum / float64(count)
 }
 
 // Duration returns the duration in seconds in a GPX segment.
@@ -1027,6 +1031,16 @@ func (seg *GPXTrackSegment) Duration() float64 {
 	return dur.Seconds()
 }
 
+// DurationWithOptions returns the duration in seconds in a GPX segment
+// without the (auto paused) stops.
+func (seg *GPXTrackSegment) DurationWithOptions(opts MovingOptions) float64 {
+	result := seg.Duration()
+	for _, stop := range seg.AutoPause(opts) {
+		result -= stop.Duration()
+	}
+	return math.Max(result, 0)
+}
+
 // Elevations returns a slice with the elevations in a GPX segment.
 func (seg *GPXTrackSegment) Elevations() []NullableFloat64 {
 	elevations := make([]NullableFloat64, len(seg.Points))
@@ -1135,11 +1149,17 @@ func (seg *GPXTrackSegment) PositionAt(t time.Time) int {
 
 // StoppedPositions returns the positions where there was a stop
 func (seg *GPXTrackSegment) StoppedPositions() []TrackPosition {
+	return seg.StoppedPositionsWithOptions(DefaultMovingOptions)
+}
+
+// StoppedPositionsWithOptions returns the positions where there was a stop
+// (the points at the end of stopped intervals)
+func (seg *GPXTrackSegment) StoppedPositionsWithOptions(opts MovingOptions) []TrackPosition {
 	result := make([]TrackPosition, 0)
+	stopped := seg.stoppedIntervals(opts)
 	for pointNo, point := range seg.Points {
 		if pointNo > 0 {
-			previousPoint := seg.Points[pointNo-1]
-			if point.SpeedBetween(&previousPoint, true) < defaultStoppedSpeedThreshold {
+			if stopped[pointNo] {
 				var trackPos TrackPosition
 				trackPos.Point = point.Point
 				trackPos.PointNo = pointNo
@@ -1154,6 +1174,11 @@ func (seg *GPXTrackSegment) StoppedPositions() []TrackPosition {
 
 // MovingData returns the moving data of a GPX segment.
 func (seg *GPXTrackSegment) MovingData() MovingData {
+	return seg.MovingDataWithOptions(DefaultMovingOptions)
+}
+
+// MovingDataWithOptions returns the moving data of a GPX segment.
+func (seg *GPXTrackSegment) MovingDataWithOptions(opts MovingOptions) MovingData {
 	var (
 		movingTime      float64
 		stoppedTime     float64
@@ -1162,6 +1187,7 @@ func (seg *GPXTrackSegment) MovingData() MovingData {
 	)
 
 	speedsDistances := make([]SpeedsAndDistances, 0)
+	stopped := seg.stoppedIntervals(opts)
 
 	for i := 1; i < len(seg.Points); i++ {
 		prev := seg.Points[i-1]
@@ -1170,14 +1196,8 @@ func (seg *GPXTrackSegment) MovingData() MovingData {
 		dist := pt.Distance3D(&prev)
 
 		timedelta := pt.Timestamp.Sub(prev.Timestamp)
-		seconds := timedelta.Seconds()
-		var speedKmh float64
-
-		if seconds > 0 {
-			speedKmh = (dist / 1000.0) / (timedelta.Seconds() / math.Pow(60, 2))
-		}
 
-		if speedKmh <= defaultStoppedSpeedThreshold {
+		if stopped[i] {
 			stoppedTime += timedelta.Seconds()
 			stoppedDistance += dist
 		} else {
@@ -1525,6 +1545,11 @@ func (trk *GPXTrack) JoinNext(segNo int) {
 
 // MovingData returns the moving data of a GPX track.
 func (trk *GPXTrack) MovingData() MovingData {
+	return trk.MovingDataWithOptions(DefaultMovingOptions)
+}
+
+// MovingDataWithOptions returns the moving data of a GPX track.
+func (trk *GPXTrack) MovingDataWithOptions(opts MovingOptions) MovingData {
 	var (
 		movingTime      float64
 		stoppedTime     float64
@@ -1534,7 +1559,7 @@ func (trk *GPXTrack) MovingData() MovingData {
 	)
 
 	for _, seg := range trk.Segments {
-		md := seg.MovingData()
+		md := seg.MovingDataWithOptions(opts)
 		movingTime += md.MovingTime
 		stoppedTime += md.StoppedTime
 		movingDistance += md.MovingDistance
@@ -1566,6 +1591,16 @@ func (trk *GPXTrack) Duration() float64 {
 	return result
 }
 
+// DurationWithOptions returns the duration of a GPX track in seconds
+// without the (auto paused) stops.
+func (trk *GPXTrack) DurationWithOptions(opts MovingOptions) float64 {
+	var result float64
+	for segmentNo := range trk.Segments {
+		result += trk.Segments[segmentNo].DurationWithOptions(opts)
+	}
+	return result
+}
+
 // UphillDownhill return the uphill and downhill values of a GPX track.
 func (trk *GPXTrack) UphillDownhill() UphillDownhill {
 	if len(trk.Segments) == 0 {
@@ -1609,9 +1644,14 @@ func (trk *GPXTrack) PositionAt(t time.Time) []TrackPosition {
 
 // StoppedPositions returns the positions where there was a stop
 func (trk *GPXTrack) StoppedPositions() []TrackPosition {
+	return trk.StoppedPositionsWithOptions(DefaultMovingOptions)
+}
+
+// StoppedPositionsWithOptions returns the positions where there was a stop
+func (trk *GPXTrack) StoppedPositionsWithOptions(opts MovingOptions) []TrackPosition {
 	result := make([]TrackPosition, 0)
 	for segmentNo, segment := range trk.Segments {
-		positions := segment.StoppedPositions()
+		positions := segment.StoppedPositionsWithOptions(opts)
 		for _, position := range positions {
 			position.SegmentNo = segmentNo
 			result = append(result, position)
@@ -184,8 +184,8 @@ type gpx10GpxPoint struct {
 	// Position info
 	Ele         NullableFloat64 `xml:"ele,omitempty"`
 	Timestamp   string          `xml:"time,omitempty"`
-	MagVar      string          `xml:"magvar,omitempty"`
-	GeoIdHeight string          `xml:"geoidheight,omitempty"`
+	MagVar      NullableFloat64 `xml:"magvar,omitempty"`
+	GeoIdHeight NullableFloat64 `xml:"geoidheight,omitempty"`
 	// Description info
 	Name  string         `xml:"name,omitempty"`
 	Cmt   string         `xml:"cmt,omitempty"`
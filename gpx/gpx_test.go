@@ -257,8 +257,8 @@ func executeSample11GpxAsserts(t *testing.T, gpxDoc *GPX) {
 	assertEquals(t, gpxDoc.Waypoints[0].Longitude, 45.6)
 	assertEquals(t, gpxDoc.Waypoints[0].Elevation.Value(), 75.1)
 	assertEquals(t, gpxDoc.Waypoints[0].Timestamp.Format(TimeFormat), "2013-01-02T02:03:00Z")
-	assertEquals(t, gpxDoc.Waypoints[0].MagneticVariation, "1.1")
-	assertEquals(t, gpxDoc.Waypoints[0].GeoidHeight, "2.0")
+	assertEquals(t, gpxDoc.Waypoints[0].MagneticVariation.Value(), 1.1)
+	assertEquals(t, gpxDoc.Waypoints[0].GeoidHeight.Value(), 2.0)
 	assertEquals(t, gpxDoc.Waypoints[0].Name, "example name")
 	assertEquals(t, gpxDoc.Waypoints[0].Comment, "example cmt")
 	assertEquals(t, gpxDoc.Waypoints[0].Description, "example desc")
@@ -293,8 +293,8 @@ func executeSample11GpxAsserts(t *testing.T, gpxDoc *GPX) {
 	assertEquals(t, gpxDoc.Routes[0].Points[0].Elevation.Value(), 75.1)
 	fmt.Println("t=", gpxDoc.Routes[0].Points[0].Timestamp)
 	assertEquals(t, gpxDoc.Routes[0].Points[0].Timestamp.Format(TimeFormat), "2013-01-02T02:03:03Z")
-	assertEquals(t, gpxDoc.Routes[0].Points[0].MagneticVariation, "1.2")
-	assertEquals(t, gpxDoc.Routes[0].Points[0].GeoidHeight, "2.1")
+	assertEquals(t, gpxDoc.Routes[0].Points[0].MagneticVariation.Value(), 1.2)
+	assertEquals(t, gpxDoc.Routes[0].Points[0].GeoidHeight.Value(), 2.1)
 	assertEquals(t, gpxDoc.Routes[0].Points[0].Name, "example name r")
 	assertEquals(t, gpxDoc.Routes[0].Points[0].Comment, "example cmt r")
 	assertEquals(t, gpxDoc.Routes[0].Points[0].Description, "example desc r")
@@ -334,8 +334,8 @@ func executeSample11GpxAsserts(t *testing.T, gpxDoc *GPX) {
 	assertEquals(t, len(gpxDoc.Tracks[0].Segments[1].Points), 0)
 	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].Elevation.Value(), 11.1)
 	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].Timestamp.Format(TimeFormat), "2013-01-01T12:00:04Z")
-	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].MagneticVariation, "12")
-	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].GeoidHeight, "13")
+	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].MagneticVariation.Value(), 12.0)
+	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].GeoidHeight.Value(), 13.0)
 	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].Name, "example name t")
 	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].Comment, "example cmt t")
 	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].Description, "example desc t")
@@ -419,8 +419,8 @@ func executeSample10GpxAsserts(t *testing.T, gpxDoc *GPX) {
 	assertEquals(t, gpxDoc.Waypoints[0].Longitude, 45.6)
 	assertEquals(t, gpxDoc.Waypoints[0].Elevation.Value(), 75.1)
 	assertEquals(t, gpxDoc.Waypoints[0].Timestamp.Format(TimeFormat), "2013-01-02T02:03:00Z")
-	assertEquals(t, gpxDoc.Waypoints[0].MagneticVariation, "1.1")
-	assertEquals(t, gpxDoc.Waypoints[0].GeoidHeight, "2.0")
+	assertEquals(t, gpxDoc.Waypoints[0].MagneticVariation.Value(), 1.1)
+	assertEquals(t, gpxDoc.Waypoints[0].GeoidHeight.Value(), 2.0)
 	assertEquals(t, gpxDoc.Waypoints[0].Name, "example name")
 	assertEquals(t, gpxDoc.Waypoints[0].Comment, "example cmt")
 	assertEquals(t, gpxDoc.Waypoints[0].Description, "example desc")
@@ -455,8 +455,8 @@ func executeSample10GpxAsserts(t *testing.T, gpxDoc *GPX) {
 	assertEquals(t, gpxDoc.Routes[0].Points[0].Elevation.Value(), 75.1)
 	fmt.Println("t=", gpxDoc.Routes[0].Points[0].Timestamp)
 	assertEquals(t, gpxDoc.Routes[0].Points[0].Timestamp.Format(TimeFormat), "2013-01-02T02:03:03Z")
-	assertEquals(t, gpxDoc.Routes[0].Points[0].MagneticVariation, "1.2")
-	assertEquals(t, gpxDoc.Routes[0].Points[0].GeoidHeight, "2.1")
+	assertEquals(t, gpxDoc.Routes[0].Points[0].MagneticVariation.Value(), 1.2)
+	assertEquals(t, gpxDoc.Routes[0].Points[0].GeoidHeight.Value(), 2.1)
 	assertEquals(t, gpxDoc.Routes[0].Points[0].Name, "example name r")
 	assertEquals(t, gpxDoc.Routes[0].Points[0].Comment, "example cmt r")
 	assertEquals(t, gpxDoc.Routes[0].Points[0].Description, "example desc r")
@@ -496,8 +496,8 @@ func executeSample10GpxAsserts(t *testing.T, gpxDoc *GPX) {
 	assertEquals(t, len(gpxDoc.Tracks[0].Segments[1].Points), 0)
 	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].Elevation.Value(), 11.1)
 	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].Timestamp.Format(TimeFormat), "2013-01-01T12:00:04Z")
-	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].MagneticVariation, "12")
-	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].GeoidHeight, "13")
+	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].MagneticVariation.Value(), 12.0)
+	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].GeoidHeight.Value(), 13.0)
 	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].Name, "example name t")
 	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].Comment, "example cmt t")
 	assertEquals(t, gpxDoc.Tracks[0].Segments[0].Points[0].Description, "example desc t")
@@ -651,6 +651,23 @@ func TestSpeedSeg(t *testing.T) {
 	}
 }
 
+func TestSpeedSegNeighbours(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 200, 100)
+	seg.Points[3].Timestamp = time.Time{}
+	assert.InDelta(t, 10, seg.Speed(0), 0.01)
+	assert.InDelta(t, 15, seg.Speed(1), 0.01)
+	// The neighbour without timestamp is ignored:
+	assert.InDelta(t, 20, seg.Speed(2), 0.01)
+	assert.Equal(t, 0.0, seg.Speed(3))
+
+	// Same time as the previous point:
+	seg.Points[2].Timestamp = seg.Points[1].Timestamp
+	assert.InDelta(t, 10, seg.Speed(1), 0.01)
+	assert.Equal(t, 0.0, seg.Speed(2))
+}
+
 func TestSegmentDuration(t *testing.T) {
 	t.Parallel()
 	g, _ := ParseFile("../test_files/file.gpx")
@@ -0,0 +1,231 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"sort"
+)
+
+// PointGradient is the (smoothed) gradient at a point
+type PointGradient struct {
+	PointNo int
+	// Distance is the 2D distance from the start in meters
+	Distance float64
+	// Percent and Degrees are null if there are no elevations around the point
+	Percent NullableFloat64
+	Degrees NullableFloat64
+}
+
+// GradientWindow is the average gradient between two distances
+type GradientWindow struct {
+	// StartDistance and EndDistance are 2D distances from the start in meters
+	StartDistance float64
+	EndDistance   float64
+	// Percent and Degrees are null if there are no elevations in the window
+	Percent NullableFloat64
+	Degrees NullableFloat64
+}
+
+func newGradientWindow(startDistance, endDistance float64, percent NullableFloat64) GradientWindow {
+	result := GradientWindow{StartDistance: startDistance, EndDistance: endDistance, Percent: percent}
+	if percent.NotNull() {
+		result.Degrees = *NewNullableFloat64(gradientDegrees(percent.Value()))
+	}
+	return result
+}
+
+// GradientBucket is the distance with a gradient in [From, To) percent
+type GradientBucket struct {
+	// From is -Inf for the first and To is +Inf for the last bucket
+	From     float64
+	To       float64
+	Distance float64
+}
+
+func gradientDegrees(percent float64) float64 {
+	return math.Atan(percent/100) * 180 / math.Pi
+}
+
+// elevationProfile contains the distances (from the start) and elevations
+// of the points with elevations
+type elevationProfile struct {
+	pointDistances []float64
+	xs             []float64
+	ys             []float64
+}
+
+func newElevationProfile(points []GPXPoint) elevationProfile {
+	var result elevationProfile
+	result.pointDistances = make([]float64, len(points))
+	var fromStart float64
+	for pointNo := range points {
+		if pointNo > 0 {
+			fromStart += points[pointNo].Distance2D(&points[pointNo-1])
+		}
+		result.pointDistances[pointNo] = fromStart
+		if points[pointNo].Elevation.NotNull() {
+			result.xs = append(result.xs, fromStart)
+			result.ys = append(result.ys, points[pointNo].Elevation.Value())
+		}
+	}
+	return result
+}
+
+func (ep elevationProfile) length() float64 {
+	if len(ep.pointDistances) == 0 {
+		return 0
+	}
+	return ep.pointDistances[len(ep.pointDistances)-1]
+}
+
+// elevationAt returns the interpolated elevation, x must be between the first and last elevation distance
+func (ep elevationProfile) elevationAt(x float64) float64 {
+	k := sort.SearchFloat64s(ep.xs, x)
+	if k >= len(ep.xs) {
+		return ep.ys[len(ep.ys)-1]
+	}
+	if k == 0 || ep.xs[k] == x {
+		return ep.ys[k]
+	}
+	ratio := (x - ep.xs[k-1]) / (ep.xs[k] - ep.xs[k-1])
+	return interpolateFloat64(ep.ys[k-1], ep.ys[k], ratio)
+}
+
+// gradient returns the average gradient in percent between two distances.
+// The interval is limited to the part with elevations.
+func (ep elevationProfile) gradient(from, to float64) NullableFloat64 {
+	if len(ep.xs) < 2 {
+		return NullableFloat64{}
+	}
+	from = math.Max(from, ep.xs[0])
+	to = math.Min(to, ep.xs[len(ep.xs)-1])
+	if to-from <= 0 {
+		return NullableFloat64{}
+	}
+	return *NewNullableFloat64((ep.elevationAt(to) - ep.elevationAt(from)) / (to - from) * 100)
+}
+
+func (ep elevationProfile) pointGradients(window float64) []PointGradient {
+	result := make([]PointGradient, len(ep.pointDistances))
+	for pointNo, distance := range ep.pointDistances {
+		from, to := distance-window/2, distance+window/2
+		if window <= 0 {
+			// Neighbouring points:
+			from, to = distance, distance
+			if pointNo > 0 {
+				from = ep.pointDistances[pointNo-1]
+			}
+			if pointNo < len(ep.pointDistances)-1 {
+				to = ep.pointDistances[pointNo+1]
+			}
+		}
+		percent := ep.gradient(from, to)
+		result[pointNo] = PointGradient{PointNo: pointNo, Distance: distance, Percent: percent}
+		if percent.NotNull() {
+			result[pointNo].Degrees = *NewNullableFloat64(gradientDegrees(percent.Value()))
+		}
+	}
+	return result
+}
+
+func (ep elevationProfile) gradientProfile(step float64) []GradientWindow {
+	result := make([]GradientWindow, 0)
+	length := ep.length()
+	if step <= 0 || length <= 0 {
+		return result
+	}
+	for from := 0.0; from < length-1e-6; from += step {
+		to := math.Min(from+step, length)
+		result = append(result, newGradientWindow(from, to, ep.gradient(from, to)))
+	}
+	return result
+}
+
+func (ep elevationProfile) gradientHistogram(step float64, bounds []float64) []GradientBucket {
+	sortedBounds := append([]float64{}, bounds...)
+	sort.Float64s(sortedBounds)
+	result := make([]GradientBucket, len(sortedBounds)+1)
+	for bucketNo := range result {
+		result[bucketNo].From, result[bucketNo].To = math.Inf(-1), math.Inf(1)
+		if bucketNo > 0 {
+			result[bucketNo].From = sortedBounds[bucketNo-1]
+		}
+		if bucketNo < len(sortedBounds) {
+			result[bucketNo].To = sortedBounds[bucketNo]
+		}
+	}
+	for _, window := range ep.gradientProfile(step) {
+		if window.Percent.Null() {
+			continue
+		}
+		bucketNo := sort.Search(len(sortedBounds), func(i int) bool { return sortedBounds[i] > window.Percent.Value() })
+		result[bucketNo].Distance += window.EndDistance - window.StartDistance
+	}
+	return result
+}
+
+func (ep elevationProfile) maxSustainedGradient(distance float64) (GradientWindow, bool) {
+	start, end, difference, found := bestWindow(ep.xs, ep.ys, distance, func(candidate, best float64) bool { return candidate > best })
+	if !found {
+		return GradientWindow{}, false
+	}
+	edgeDistance := func(edge windowEdge) float64 {
+		if edge.ratio > 0 && edge.index+1 < len(ep.xs) {
+			return interpolateFloat64(ep.xs[edge.index], ep.xs[edge.index+1], edge.ratio)
+		}
+		return ep.xs[edge.index]
+	}
+	return newGradientWindow(edgeDistance(start), edgeDistance(end), *NewNullableFloat64(difference / distance * 100)), true
+}
+
+// ----------------------------------------------------------------------------------------------------
+
+// Gradients returns the gradient at every point, computed over the given
+// distance (in meters) centered at the point. With a zero window the
+// neighbouring points are used. Points without elevation are ignored.
+func (seg *GPXTrackSegment) Gradients(window float64) []PointGradient {
+	return newElevationProfile(seg.Points).pointGradients(window)
+}
+
+// GradientProfile returns the average gradients for every step meters
+func (seg *GPXTrackSegment) GradientProfile(step float64) []GradientWindow {
+	return newElevationProfile(seg.Points).gradientProfile(step)
+}
+
+// GradientHistogram returns the distance spent in every gradient bucket. The
+// bucket bounds are in percent, the gradients are computed for every step meters.
+func (seg *GPXTrackSegment) GradientHistogram(step float64, bounds []float64) []GradientBucket {
+	return newElevationProfile(seg.Points).gradientHistogram(step, bounds)
+}
+
+// MaxSustainedGradient returns the steepest uphill window of the given distance (in meters)
+func (seg *GPXTrackSegment) MaxSustainedGradient(distance float64) (GradientWindow, bool) {
+	return newElevationProfile(seg.Points).maxSustainedGradient(distance)
+}
+
+// Gradients returns the gradient at every point, computed over the given
+// distance (in meters) centered at the point. With a zero window the
+// neighbouring points are used. Points without elevation are ignored.
+func (rte *GPXRoute) Gradients(window float64) []PointGradient {
+	return newElevationProfile(rte.Points).pointGradients(window)
+}
+
+// GradientProfile returns the average gradients for every step meters
+func (rte *GPXRoute) GradientProfile(step float64) []GradientWindow {
+	return newElevationProfile(rte.Points).gradientProfile(step)
+}
+
+// GradientHistogram returns the distance spent in every gradient bucket. The
+// bucket bounds are in percent, the gradients are computed for every step meters.
+func (rte *GPXRoute) GradientHistogram(step float64, bounds []float64) []GradientBucket {
+	return newElevationProfile(rte.Points).gradientHistogram(step, bounds)
+}
+
+// MaxSustainedGradient returns the steepest uphill window of the given distance (in meters)
+func (rte *GPXRoute) MaxSustainedGradient(distance float64) (GradientWindow, bool) {
+	return newElevationProfile(rte.Points).maxSustainedGradient(distance)
+}
@@ -0,0 +1,84 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGradients(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestElevationSegment(100, 100, 110, 120, 120, 100)
+	seg.Points[3].Elevation.SetNull()
+
+	gradients := seg.Gradients(0)
+	assert.Equal(t, 5, len(gradients))
+	percents := make([]float64, len(gradients))
+	for n := range gradients {
+		percents[n] = gradients[n].Percent.Value()
+	}
+	assert.InDeltaSlice(t, []float64{10, 10, 0, -10, -10}, percents, 0.001)
+	assert.InDelta(t, 300, gradients[3].Distance, 0.1)
+	assert.InDelta(t, math.Atan(0.1)*180/math.Pi, gradients[0].Degrees.Value(), 0.001)
+
+	gradients = seg.Gradients(200)
+	assert.InDelta(t, 10, gradients[0].Percent.Value(), 0.001)
+	// The missing elevation at 300m is interpolated (110m):
+	assert.InDelta(t, 0, gradients[2].Percent.Value(), 0.001)
+
+	var empty GPXTrackSegment
+	empty.AppendPoint(&GPXPoint{Point: Point{Latitude: 45, Longitude: 13}})
+	empty.AppendPoint(&GPXPoint{Point: Point{Latitude: 45.001, Longitude: 13}})
+	for _, gradient := range empty.Gradients(0) {
+		assert.True(t, gradient.Percent.Null())
+		assert.True(t, gradient.Degrees.Null())
+	}
+}
+
+func TestGradientProfileAndHistogram(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestElevationSegment(100, 100, 110, 120, 120, 100)
+	rte := GPXRoute{Points: seg.Points}
+
+	profile := rte.GradientProfile(150)
+	if assert.Equal(t, 3, len(profile)) {
+		assert.InDelta(t, 10, profile[0].Percent.Value(), 0.001)
+		assert.InDelta(t, 150, profile[1].StartDistance, 0.1)
+		assert.InDelta(t, 3.333, profile[1].Percent.Value(), 0.001)
+		assert.InDelta(t, 400, profile[2].EndDistance, 0.1)
+		assert.InDelta(t, -20, profile[2].Percent.Value(), 0.001)
+	}
+
+	histogram := seg.GradientHistogram(100, []float64{5, -5, 1})
+	if assert.Equal(t, 4, len(histogram)) {
+		assert.True(t, math.IsInf(histogram[0].From, -1))
+		assert.Equal(t, -5.0, histogram[0].To)
+		assert.InDelta(t, 100, histogram[0].Distance, 0.1)
+		assert.InDelta(t, 100, histogram[1].Distance, 0.1)
+		assert.InDelta(t, 0, histogram[2].Distance, 0.1)
+		assert.InDelta(t, 200, histogram[3].Distance, 0.1)
+		assert.True(t, math.IsInf(histogram[3].To, 1))
+	}
+}
+
+func TestMaxSustainedGradient(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestElevationSegment(100, 100, 105, 125, 135, 135)
+	window, found := seg.MaxSustainedGradient(200)
+	assert.True(t, found)
+	assert.InDelta(t, 15, window.Percent.Value(), 0.001)
+	assert.InDelta(t, 100, window.StartDistance, 0.1)
+	assert.InDelta(t, 300, window.EndDistance, 0.1)
+
+	_, found = seg.MaxSustainedGradient(1000)
+	assert.False(t, found)
+}
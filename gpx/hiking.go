@@ -0,0 +1,144 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"time"
+)
+
+// HikingTimeRule is a rule for estimating the hiking time from the distance
+// and elevation change
+type HikingTimeRule int
+
+const (
+	// Naismith is 5 km/h plus 1 hour per 600 m of ascent, with the Langmuir
+	// corrections for descents (minus 10 minutes per 300 m of descent between
+	// 5 and 12 degrees, plus 10 minutes per 300 m on steeper descents)
+	Naismith HikingTimeRule = iota
+	// Tobler is Tobler's hiking function, 6*e^(-3.5*|gradient+0.05|) km/h
+	Tobler
+	// DIN33466 is 4 km/h horizontally, 300 m/h ascent and 500 m/h descent. The
+	// total is the bigger plus half of the smaller of the two times.
+	DIN33466
+)
+
+func (r HikingTimeRule) String() string {
+	switch r {
+	case Naismith:
+		return "Naismith"
+	case Tobler:
+		return "Tobler"
+	case DIN33466:
+		return "DIN 33466"
+	}
+	return ""
+}
+
+// LegDuration returns the estimated time in seconds for the 2D distance,
+// ascent and descent (all in meters)
+func (r HikingTimeRule) LegDuration(distance, ascent, descent float64) float64 {
+	switch r {
+	case Tobler:
+		if distance <= 0 {
+			return 0
+		}
+		speed := 6 * math.Exp(-3.5*math.Abs((ascent-descent)/distance+0.05)) / 3.6
+		return distance / speed
+	case DIN33466:
+		horizontal := distance / 4000 * 3600
+		vertical := (ascent/300 + descent/500) * 3600
+		return math.Max(horizontal, vertical) + math.Min(horizontal, vertical)/2
+	}
+	result := distance/5000*3600 + ascent/600*3600
+	if descent > 0 {
+		degrees := 90.0
+		if distance > 0 {
+			degrees = math.Atan(descent/distance) * 180 / math.Pi
+		}
+		if degrees > 12 {
+			result += descent / 300 * 600
+		} else if degrees >= 5 {
+			result -= descent / 300 * 600
+		}
+	}
+	return result
+}
+
+// estimatedLegDurations returns the estimated seconds between every two
+// points (the first value is always 0). Legs without elevations are flat.
+func estimatedLegDurations(points []GPXPoint, rule HikingTimeRule) []float64 {
+	result := make([]float64, len(points))
+	for pointNo := 1; pointNo < len(points); pointNo++ {
+		previous, point := &points[pointNo-1], &points[pointNo]
+		var ascent, descent float64
+		if previous.Elevation.NotNull() && point.Elevation.NotNull() {
+			difference := point.Elevation.Value() - previous.Elevation.Value()
+			ascent, descent = math.Max(difference, 0), math.Max(-difference, 0)
+		}
+		result[pointNo] = rule.LegDuration(point.Distance2D(previous), ascent, descent)
+	}
+	return result
+}
+
+func estimateDuration(points []GPXPoint, rule HikingTimeRule) float64 {
+	var result float64
+	for _, seconds := range estimatedLegDurations(points, rule) {
+		result += seconds
+	}
+	return result
+}
+
+func addEstimatedTimestamps(points []GPXPoint, start time.Time, rule HikingTimeRule) time.Time {
+	var seconds float64
+	at := func() time.Time { return start.Add(time.Duration(math.Round(seconds * float64(time.Second)))) }
+	for pointNo, legSeconds := range estimatedLegDurations(points, rule) {
+		seconds += legSeconds
+		points[pointNo].Timestamp = at().UTC()
+	}
+	return at()
+}
+
+// EstimateDuration returns the estimated hiking time of the route in seconds
+func (rte *GPXRoute) EstimateDuration(rule HikingTimeRule) float64 {
+	return estimateDuration(rte.Points, rule)
+}
+
+// AddEstimatedTimestamps sets the route point timestamps using the estimated
+// hiking times from the start time
+func (rte *GPXRoute) AddEstimatedTimestamps(start time.Time, rule HikingTimeRule) {
+	addEstimatedTimestamps(rte.Points, start, rule)
+}
+
+// EstimateDuration returns the estimated hiking time of the segment in seconds
+func (seg *GPXTrackSegment) EstimateDuration(rule HikingTimeRule) float64 {
+	return estimateDuration(seg.Points, rule)
+}
+
+// AddEstimatedTimestamps sets the point timestamps using the estimated
+// hiking times from the start time
+func (seg *GPXTrackSegment) AddEstimatedTimestamps(start time.Time, rule HikingTimeRule) {
+	addEstimatedTimestamps(seg.Points, start, rule)
+}
+
+// EstimateDuration returns the estimated hiking time of the track in seconds
+// (gaps between segments are not counted)
+func (trk *GPXTrack) EstimateDuration(rule HikingTimeRule) float64 {
+	var result float64
+	for segmentNo := range trk.Segments {
+		result += trk.Segments[segmentNo].EstimateDuration(rule)
+	}
+	return result
+}
+
+// AddEstimatedTimestamps sets the point timestamps using the estimated
+// hiking times from the start time. Every segment starts where the previous
+// one ended.
+func (trk *GPXTrack) AddEstimatedTimestamps(start time.Time, rule HikingTimeRule) {
+	for segmentNo := range trk.Segments {
+		start = addEstimatedTimestamps(trk.Segments[segmentNo].Points, start, rule)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHikingTimeRules(t *testing.T) {
+	t.Parallel()
+
+	// Flat:
+	assert.InDelta(t, 3600, Naismith.LegDuration(5000, 0, 0), 0.001)
+	assert.InDelta(t, 3600, DIN33466.LegDuration(4000, 0, 0), 0.001)
+	assert.InDelta(t, 5000/(6*math.Exp(-3.5*0.05)/3.6), Tobler.LegDuration(5000, 0, 0), 0.001)
+
+	// Uphill:
+	assert.InDelta(t, 7200, Naismith.LegDuration(5000, 600, 0), 0.001)
+	assert.InDelta(t, 3600+900, DIN33466.LegDuration(4000, 150, 0), 0.001)
+	assert.InDelta(t, 3600+900, DIN33466.LegDuration(2000, 300, 0), 0.001)
+
+	// Langmuir corrections:
+	assert.InDelta(t, 3600-1200, Naismith.LegDuration(5000, 0, 600), 0.001)
+	assert.InDelta(t, 3600, Naismith.LegDuration(5000, 0, 100), 0.001)
+	assert.InDelta(t, 720+1200, Naismith.LegDuration(1000, 0, 600), 0.001)
+
+	// Tobler is fastest at a slight descent:
+	assert.True(t, Tobler.LegDuration(1000, 0, 50) < Tobler.LegDuration(1000, 0, 0))
+	assert.True(t, Tobler.LegDuration(1000, 0, 50) < Tobler.LegDuration(1000, 0, 150))
+
+	assert.Equal(t, "DIN 33466", DIN33466.String())
+}
+
+func TestRouteEstimatedTimestamps(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestElevationSegment(1000, 100, 400, 400, 400)
+	seg.Points[3].Elevation.SetNull()
+	rte := GPXRoute{Points: seg.Points}
+	for pointNo := range rte.Points {
+		rte.Points[pointNo].Timestamp = time.Time{}
+	}
+
+	assert.InDelta(t, 3*720+1800, rte.EstimateDuration(Naismith), 0.1)
+
+	start := time.Date(2020, 1, 1, 10, 0, 0, 0, time.FixedZone("CET", 3600))
+	rte.AddEstimatedTimestamps(start, Naismith)
+	assert.Equal(t, time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC), rte.Points[0].Timestamp)
+	assert.Equal(t, time.UTC, rte.Points[0].Timestamp.Location())
+	assert.InDelta(t, 720+1800, rte.Points[1].Timestamp.Sub(start).Seconds(), 0.1)
+	assert.InDelta(t, 3*720+1800, rte.Points[3].Timestamp.Sub(start).Seconds(), 0.1)
+}
+
+func TestTrackEstimatedTimestamps(t *testing.T) {
+	t.Parallel()
+
+	seg1 := newTestElevationSegment(1000, 100, 100)
+	seg2 := newTestElevationSegment(2000, 100, 100)
+	trk := GPXTrack{}
+	trk.AppendSegment(&seg1)
+	trk.AppendSegment(&seg2)
+
+	assert.InDelta(t, 3*900, trk.EstimateDuration(DIN33466), 0.1)
+	trk.AddEstimatedTimestamps(testStartTime, DIN33466)
+	assert.Equal(t, testStartTime, trk.Segments[1].Points[0].Timestamp.Add(-900*time.Second))
+	assert.InDelta(t, 3*900, trk.Segments[1].Points[1].Timestamp.Sub(testStartTime).Seconds(), 0.1)
+}
@@ -0,0 +1,184 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// InterpolatedLocation is a location on a track interpolated between two
+// consecutive track points
+type InterpolatedLocation struct {
+	GPXPoint
+	TrackNo   int
+	SegmentNo int
+	// PointNo is the index of the track point before (or at) the location
+	PointNo int
+	// GroundSpeed in m/s between the surrounding track points (Speed() is
+	// the speed measured by the device, see GPXPoint.Speed)
+	GroundSpeed float64
+	// Heading in degrees from north between the surrounding track points
+	Heading float64
+}
+
+func newInterpolatedLocation(seg *GPXTrackSegment, pointNo int, ratio float64) InterpolatedLocation {
+	result := InterpolatedLocation{TrackNo: -1, SegmentNo: -1, PointNo: pointNo}
+	if pointNo >= len(seg.Points)-1 {
+		result.GPXPoint = seg.Points[pointNo]
+		if pointNo > 0 {
+			result.GroundSpeed, result.Heading = speedAndHeading(&seg.Points[pointNo-1], &seg.Points[pointNo])
+		}
+		return result
+	}
+
+	point1, point2 := &seg.Points[pointNo], &seg.Points[pointNo+1]
+	result.GPXPoint = interpolatePoints(point1, point2, ratio)
+	result.GroundSpeed, result.Heading = speedAndHeading(point1, point2)
+	return result
+}
+
+func speedAndHeading(point1, point2 *GPXPoint) (float64, float64) {
+	var speed, heading float64
+	if seconds := point2.Timestamp.Sub(point1.Timestamp).Seconds(); seconds > 0 && hasTimestamp(point1) {
+		speed = point2.Distance2D(point1) / seconds
+	}
+	if point1.Latitude != point2.Latitude || point1.Longitude != point2.Longitude {
+		heading = AngleFromNorth(point1.Point, point2.Point, false)
+	}
+	if math.IsNaN(heading) {
+		heading = 0
+	}
+	return speed, heading
+}
+
+// LocationAtTime returns the location interpolated at time t. Found is
+// false if t is outside the segment time bounds.
+func (seg *GPXTrackSegment) LocationAtTime(t time.Time) (location InterpolatedLocation, found bool) {
+	pointsNo := len(seg.Points)
+	if pointsNo == 0 || !hasTimestamp(&seg.Points[0]) {
+		return
+	}
+	first, last := seg.Points[0].Timestamp, seg.Points[pointsNo-1].Timestamp
+	if t.Before(first) || t.After(last) {
+		return
+	}
+
+	// First point after t:
+	next := sort.Search(pointsNo, func(i int) bool {
+		return seg.Points[i].Timestamp.After(t)
+	})
+	if next == 0 {
+		return
+	}
+	pointNo := next - 1
+	var ratio float64
+	if next < pointsNo {
+		delta := seg.Points[next].Timestamp.Sub(seg.Points[pointNo].Timestamp)
+		if delta > 0 {
+			ratio = float64(t.Sub(seg.Points[pointNo].Timestamp)) / float64(delta)
+		}
+	}
+	return newInterpolatedLocation(seg, pointNo, ratio), true
+}
+
+// LocationAtDistance returns the location interpolated at the given 2D
+// distance (in meters) from the segment start. Found is false if the
+// distance is outside the segment.
+func (seg *GPXTrackSegment) LocationAtDistance(meters float64) (location InterpolatedLocation, found bool) {
+	if len(seg.Points) == 0 || meters < 0 {
+		return
+	}
+
+	var fromStart float64
+	for pointNo := 1; pointNo < len(seg.Points); pointNo++ {
+		d := seg.Points[pointNo].Distance2D(&seg.Points[pointNo-1])
+		if fromStart+d >= meters {
+			var ratio float64
+			if d > 0 {
+				ratio = (meters - fromStart) / d
+			}
+			return newInterpolatedLocation(seg, pointNo-1, ratio), true
+		}
+		fromStart += d
+	}
+
+	if meters == fromStart {
+		return newInterpolatedLocation(seg, len(seg.Points)-1, 0), true
+	}
+	return
+}
+
+// LocationAtTime returns the locations interpolated at time t on all track segments
+func (trk *GPXTrack) LocationAtTime(t time.Time) []InterpolatedLocation {
+	results := make([]InterpolatedLocation, 0)
+	for segmentNo := range trk.Segments {
+		if location, found := trk.Segments[segmentNo].LocationAtTime(t); found {
+			location.SegmentNo = segmentNo
+			results = append(results, location)
+		}
+	}
+	return results
+}
+
+// LocationAtDistance returns the location interpolated at the given 2D
+// distance from the track start. Distances are summed over all segments
+// (gaps between segments are not counted).
+func (trk *GPXTrack) LocationAtDistance(meters float64) (location InterpolatedLocation, found bool) {
+	if meters < 0 {
+		return
+	}
+	for segmentNo := range trk.Segments {
+		seg := &trk.Segments[segmentNo]
+		if len(seg.Points) == 0 {
+			continue
+		}
+		length := seg.Length2D()
+		if meters <= length {
+			location, found = seg.LocationAtDistance(meters)
+			location.SegmentNo = segmentNo
+			return
+		}
+		meters -= length
+	}
+	return
+}
+
+// LocationAtTime returns the locations interpolated at time t on all tracks
+func (g *GPX) LocationAtTime(t time.Time) []InterpolatedLocation {
+	results := make([]InterpolatedLocation, 0)
+	for trackNo := range g.Tracks {
+		for _, location := range g.Tracks[trackNo].LocationAtTime(t) {
+			location.TrackNo = trackNo
+			results = append(results, location)
+		}
+	}
+	return results
+}
+
+// LocationAtDistance returns the location interpolated at the given 2D
+// distance from the start of the first track. Distances are summed over all
+// tracks and segments.
+func (g *GPX) LocationAtDistance(meters float64) (location InterpolatedLocation, found bool) {
+	if meters < 0 {
+		return
+	}
+	for trackNo := range g.Tracks {
+		trk := &g.Tracks[trackNo]
+		if trk.GetTrackPointsNo() == 0 {
+			continue
+		}
+		length := trk.Length2D()
+		if meters <= length {
+			location, found = trk.LocationAtDistance(meters)
+			location.TrackNo = trackNo
+			return
+		}
+		meters -= length
+	}
+	return
+}
@@ -0,0 +1,95 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentLocationAtTime(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 200)
+	seg.Points[2].Elevation.SetValue(300)
+	seg.Points[1].SetSpeed(4)
+	seg.Points[2].SetSpeed(6)
+
+	location, found := seg.LocationAtTime(testStartTime.Add(15 * time.Second))
+	assert.True(t, found)
+	assert.Equal(t, 1, location.PointNo)
+	assert.Equal(t, testStartTime.Add(15*time.Second), location.Timestamp)
+	assert.InDelta(t, 200, location.Distance2D(&seg.Points[0]), 0.01)
+	assert.InDelta(t, 200, location.Elevation.Value(), 0.0001)
+	assert.InDelta(t, 20, location.GroundSpeed, 0.001)
+	assert.InDelta(t, 0, location.Heading, 0.0001)
+	// The speed measured by the device:
+	speed := location.Speed()
+	assert.InDelta(t, 5, speed.Value(), 0.0001)
+
+	location, found = seg.LocationAtTime(testStartTime.Add(20 * time.Second))
+	assert.True(t, found)
+	assert.Equal(t, 2, location.PointNo)
+	assert.Equal(t, seg.Points[2].Latitude, location.Latitude)
+
+	_, found = seg.LocationAtTime(testStartTime.Add(-time.Second))
+	assert.False(t, found)
+	_, found = seg.LocationAtTime(testStartTime.Add(21 * time.Second))
+	assert.False(t, found)
+}
+
+func TestSegmentLocationAtDistance(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 200)
+
+	location, found := seg.LocationAtDistance(50)
+	assert.True(t, found)
+	assert.Equal(t, 0, location.PointNo)
+	assert.Equal(t, testStartTime.Add(5*time.Second), location.Timestamp)
+	assert.InDelta(t, 10, location.GroundSpeed, 0.001)
+
+	location, found = seg.LocationAtDistance(0)
+	assert.True(t, found)
+	assert.Equal(t, seg.Points[0].Latitude, location.Latitude)
+
+	_, found = seg.LocationAtDistance(301)
+	assert.False(t, found)
+}
+
+func TestGpxLocationAtTimeAndDistance(t *testing.T) {
+	t.Parallel()
+
+	seg1 := newTestSegment(10, 100)
+	seg2 := newTestSegment(10, 100)
+	for pointNo := range seg2.Points {
+		seg2.Points[pointNo].Timestamp = seg2.Points[pointNo].Timestamp.Add(time.Hour)
+		seg2.Points[pointNo].Longitude = 14
+	}
+
+	g := GPX{}
+	g.AppendTrack(&GPXTrack{})
+	g.AppendSegment(&seg1)
+	g.AppendTrack(&GPXTrack{})
+	g.AppendSegment(&seg2)
+
+	locations := g.LocationAtTime(testStartTime.Add(time.Hour + 5*time.Second))
+	assert.Equal(t, 1, len(locations))
+	assert.Equal(t, 1, locations[0].TrackNo)
+	assert.Equal(t, 0, locations[0].SegmentNo)
+	assert.Equal(t, 14.0, locations[0].Longitude)
+
+	location, found := g.LocationAtDistance(150)
+	assert.True(t, found)
+	assert.Equal(t, 1, location.TrackNo)
+	assert.Equal(t, 0, location.SegmentNo)
+	assert.InDelta(t, 50, location.Distance2D(&seg2.Points[0]), 0.01)
+
+	_, found = g.LocationAtDistance(250)
+	assert.False(t, found)
+}
@@ -0,0 +1,342 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WGS84 ellipsoid and geomagnetic reference radius (in km) used by the World Magnetic Model
+const (
+	wmmSemiMajorAxis   = 6378.137
+	wmmFlattening      = 1 / 298.257223563
+	wmmReferenceRadius = 6371.2
+)
+
+// validMagneticVariation returns the magnetic variation if it is within the
+// GPX degreesType range (0 <= value < 360), null otherwise
+func validMagneticVariation(variation NullableFloat64) NullableFloat64 {
+	if variation.Null() {
+		return variation
+	}
+	if value := variation.Value(); !(value >= 0 && value < 360) {
+		return NullableFloat64{}
+	}
+	return variation
+}
+
+// MagneticBearing converts a bearing relative to the geographic north (for
+// example from AngleFromNorth) into a bearing relative to the magnetic
+// north. Declination is in degrees, positive east.
+func MagneticBearing(trueBearing, declination float64) float64 {
+	result := math.Mod(trueBearing-declination, 360)
+	if result < 0 {
+		result += 360
+	}
+	return result
+}
+
+// ----------------------------------------------------------------------------------------------------
+
+// magneticModelValidity is the number of years (from the epoch) a World
+// Magnetic Model is valid
+const magneticModelValidity = 5
+
+// MagneticModel is a spherical harmonic model of the Earth's main magnetic
+// field loaded from a World Magnetic Model coefficients file (WMM.COF). The
+// coefficients are not included, download the current model (WMM2025, valid
+// from 2025.0 to 2030.0) from https://www.ncei.noaa.gov/products/world-magnetic-model
+// (the WMM2020 file in test_files is test data only).
+type MagneticModel struct {
+	Name  string
+	Epoch float64
+
+	maxDegree int
+	// Coefficients (in nT) and secular variations (in nT/year) indexed by [n][m]:
+	g    [][]float64
+	h    [][]float64
+	gDot [][]float64
+	hDot [][]float64
+}
+
+// ParseMagneticModelFile loads a World Magnetic Model coefficients file.
+func ParseMagneticModelFile(fileName string) (*MagneticModel, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseMagneticModel(f)
+}
+
+// ParseMagneticModel parses World Magnetic Model coefficients in the
+// WMM.COF format: a header line with the epoch and model name, followed by
+// "n m g h gDot hDot" lines.
+func ParseMagneticModel(reader io.Reader) (*MagneticModel, error) {
+	scanner := bufio.NewScanner(reader)
+
+	model := new(MagneticModel)
+	type coefficient struct {
+		n, m             int
+		g, h, gDot, hDot float64
+	}
+	coefficients := make([]coefficient, 0)
+
+	header := true
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		if strings.HasPrefix(line, "9999") {
+			break
+		}
+		fields := strings.Fields(line)
+		if header {
+			epoch, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid magnetic model epoch %s", fields[0])
+			}
+			model.Epoch = epoch
+			if len(fields) > 1 {
+				model.Name = fields[1]
+			}
+			header = false
+			continue
+		}
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("invalid magnetic model line: %s", line)
+		}
+		var c coefficient
+		var err error
+		if c.n, err = strconv.Atoi(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid magnetic model line: %s", line)
+		}
+		if c.m, err = strconv.Atoi(fields[1]); err != nil || c.m > c.n || c.m < 0 {
+			return nil, fmt.Errorf("invalid magnetic model line: %s", line)
+		}
+		values := make([]float64, 4)
+		for i := range values {
+			if values[i], err = strconv.ParseFloat(fields[2+i], 64); err != nil {
+				return nil, fmt.Errorf("invalid magnetic model line: %s", line)
+			}
+		}
+		c.g, c.h, c.gDot, c.hDot = values[0], values[1], values[2], values[3]
+		coefficients = append(coefficients, c)
+		if c.n > model.maxDegree {
+			model.maxDegree = c.n
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if header || len(coefficients) == 0 {
+		return nil, errors.New("empty magnetic model")
+	}
+
+	size := model.maxDegree + 1
+	model.g, model.h = newTriangularMatrix(size), newTriangularMatrix(size)
+	model.gDot, model.hDot = newTriangularMatrix(size), newTriangularMatrix(size)
+	for _, c := range coefficients {
+		model.g[c.n][c.m] = c.g
+		model.h[c.n][c.m] = c.h
+		model.gDot[c.n][c.m] = c.gDot
+		model.hDot[c.n][c.m] = c.hDot
+	}
+
+	return model, nil
+}
+
+func newTriangularMatrix(size int) [][]float64 {
+	result := make([][]float64, size)
+	for n := range result {
+		result[n] = make([]float64, n+1)
+	}
+	return result
+}
+
+// decimalYear returns the time as a fractional year (for example 2020.5)
+func decimalYear(t time.Time) float64 {
+	t = t.UTC()
+	start := time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(t.Year()+1, 1, 1, 0, 0, 0, 0, time.UTC)
+	return float64(t.Year()) + t.Sub(start).Seconds()/end.Sub(start).Seconds()
+}
+
+// MagneticField contains the magnetic field components (in nT) in the local
+// geodetic frame
+type MagneticField struct {
+	// North component
+	X float64
+	// East component
+	Y float64
+	// Down component
+	Z float64
+}
+
+// Declination returns the angle between the geographic and magnetic north in degrees (positive east).
+func (mf MagneticField) Declination() float64 {
+	return math.Atan2(mf.Y, mf.X) * 180 / math.Pi
+}
+
+// Inclination returns the dip angle of the field in degrees (positive down).
+func (mf MagneticField) Inclination() float64 {
+	return math.Atan2(mf.Z, math.Hypot(mf.X, mf.Y)) * 180 / math.Pi
+}
+
+// TotalIntensity returns the total field intensity in nT.
+func (mf MagneticField) TotalIntensity() float64 {
+	return math.Sqrt(mf.X*mf.X + mf.Y*mf.Y + mf.Z*mf.Z)
+}
+
+// checkTime returns an error if the time is outside the model validity (5
+// years from the epoch)
+func (mm *MagneticModel) checkTime(t time.Time) error {
+	if year := decimalYear(t); year < mm.Epoch || year >= mm.Epoch+magneticModelValidity {
+		return fmt.Errorf("time %s outside the magnetic model %s validity (%.1f to %.1f)", t.Format(time.RFC3339), mm.Name, mm.Epoch, mm.Epoch+magneticModelValidity)
+	}
+	return nil
+}
+
+// Field computes the magnetic field for the given geodetic location,
+// altitude above the WGS84 ellipsoid (in meters) and time. Returns an error
+// if the time is outside the model validity.
+func (mm *MagneticModel) Field(latitude, longitude, altitude float64, t time.Time) (MagneticField, error) {
+	if err := mm.checkTime(t); err != nil {
+		return MagneticField{}, err
+	}
+	dt := decimalYear(t) - mm.Epoch
+
+	// Geodetic to geocentric spherical coordinates:
+	lat := ToRad(latitude)
+	lon := ToRad(longitude)
+	altitudeKm := altitude / 1000
+	e2 := wmmFlattening * (2 - wmmFlattening)
+	rc := wmmSemiMajorAxis / math.Sqrt(1-e2*math.Sin(lat)*math.Sin(lat))
+	p := (rc + altitudeKm) * math.Cos(lat)
+	z := (rc*(1-e2) + altitudeKm) * math.Sin(lat)
+	r := math.Hypot(p, z)
+	geocentricLat := math.Asin(z / r)
+
+	// Colatitude:
+	cosTheta := math.Sin(geocentricLat)
+	sinTheta := math.Cos(geocentricLat)
+
+	size := mm.maxDegree + 1
+	// Gauss normalized associated Legendre functions and their derivatives by theta:
+	pnm, dpnm := newTriangularMatrix(size), newTriangularMatrix(size)
+	pnm[0][0] = 1
+	for n := 1; n < size; n++ {
+		for m := 0; m <= n; m++ {
+			if n == m {
+				pnm[n][m] = sinTheta * pnm[n-1][m-1]
+				dpnm[n][m] = sinTheta*dpnm[n-1][m-1] + cosTheta*pnm[n-1][m-1]
+			} else {
+				var k, pPrev, dpPrev float64
+				if n > 1 && m <= n-2 {
+					k = float64((n-1)*(n-1)-m*m) / float64((2*n-1)*(2*n-3))
+					pPrev, dpPrev = pnm[n-2][m], dpnm[n-2][m]
+				}
+				pnm[n][m] = cosTheta*pnm[n-1][m] - k*pPrev
+				dpnm[n][m] = cosTheta*dpnm[n-1][m] - sinTheta*pnm[n-1][m] - k*dpPrev
+			}
+		}
+	}
+
+	// Schmidt semi-normalization factors:
+	schmidt := newTriangularMatrix(size)
+	schmidt[0][0] = 1
+	for n := 1; n < size; n++ {
+		schmidt[n][0] = schmidt[n-1][0] * float64(2*n-1) / float64(n)
+		for m := 1; m <= n; m++ {
+			factor := 1.0
+			if m == 1 {
+				factor = 2
+			}
+			schmidt[n][m] = schmidt[n][m-1] * math.Sqrt(float64(n-m+1)*factor/float64(n+m))
+		}
+	}
+
+	var br, bTheta, bLambda float64
+	for n := 1; n < size; n++ {
+		radiusRatio := math.Pow(wmmReferenceRadius/r, float64(n+2))
+		for m := 0; m <= n; m++ {
+			g := (mm.g[n][m] + dt*mm.gDot[n][m]) * schmidt[n][m]
+			h := (mm.h[n][m] + dt*mm.hDot[n][m]) * schmidt[n][m]
+			cosML := math.Cos(float64(m) * lon)
+			sinML := math.Sin(float64(m) * lon)
+
+			br += float64(n+1) * radiusRatio * (g*cosML + h*sinML) * pnm[n][m]
+			bTheta -= radiusRatio * (g*cosML + h*sinML) * dpnm[n][m]
+			bLambda += radiusRatio * float64(m) * (g*sinML - h*cosML) * pnm[n][m]
+		}
+	}
+	if sinTheta > 1e-10 {
+		bLambda /= sinTheta
+	}
+
+	// Spherical to geodetic frame:
+	xs, ys, zs := -bTheta, bLambda, -br
+	psi := geocentricLat - lat
+	return MagneticField{
+		X: xs*math.Cos(psi) - zs*math.Sin(psi),
+		Y: ys,
+		Z: xs*math.Sin(psi) + zs*math.Cos(psi),
+	}, nil
+}
+
+// Declination returns the magnetic declination in degrees (positive east)
+// for the given location, altitude above the WGS84 ellipsoid (in meters)
+// and time. Returns an error if the time is outside the model validity.
+func (mm *MagneticModel) Declination(latitude, longitude, altitude float64, t time.Time) (float64, error) {
+	field, err := mm.Field(latitude, longitude, altitude, t)
+	if err != nil {
+		return 0, err
+	}
+	return field.Declination(), nil
+}
+
+// ----------------------------------------------------------------------------------------------------
+
+// FillMagneticVariation computes the magnetic variation for all points.
+// Point timestamps are used where available, defaultTime otherwise. Nothing
+// is changed if a time is outside the model validity.
+func (g *GPX) FillMagneticVariation(model *MagneticModel, defaultTime time.Time) error {
+	pointTime := func(point *GPXPoint) time.Time {
+		if point.Timestamp.Year() > 1 {
+			return point.Timestamp
+		}
+		return defaultTime
+	}
+	var err error
+	g.ExecuteOnAllPoints(func(point *GPXPoint) {
+		if err == nil {
+			err = model.checkTime(pointTime(point))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	g.ExecuteOnAllPoints(func(point *GPXPoint) {
+		var altitude float64
+		if point.Elevation.NotNull() {
+			altitude = point.Elevation.Value()
+		}
+		declination, _ := model.Declination(point.Latitude, point.Longitude, altitude, pointTime(point))
+		// East declinations are 0..180, west 180..360:
+		point.MagneticVariation = *NewNullableFloat64(math.Mod(declination+360, 360))
+	})
+	return nil
+}
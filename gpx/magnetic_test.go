@@ -0,0 +1,176 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseTestMagneticModel(t *testing.T, coefficients string) *MagneticModel {
+	model, err := ParseMagneticModel(strings.NewReader("    2020.0            TEST        01/01/2020\n" + coefficients + "\n999999999999999999999999999999\n"))
+	assert.Nil(t, err)
+	return model
+}
+
+func TestValidMagneticVariation(t *testing.T) {
+	t.Parallel()
+
+	for _, valid := range []float64{0, 12.5, 359.9} {
+		variation := validMagneticVariation(*NewNullableFloat64(valid))
+		assert.Equal(t, valid, variation.Value())
+	}
+	for _, invalid := range []float64{-3, 360, 725, math.NaN(), math.Inf(1)} {
+		variation := validMagneticVariation(*NewNullableFloat64(invalid))
+		assert.True(t, variation.Null(), "%f", invalid)
+	}
+	null := validMagneticVariation(NullableFloat64{})
+	assert.True(t, null.Null())
+}
+
+func TestParseInvalidMagneticVariation(t *testing.T) {
+	t.Parallel()
+
+	g, err := ParseString(`<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+<wpt lat="1" lon="2"><magvar>-5.5</magvar></wpt>
+<wpt lat="1" lon="2"><magvar>x</magvar></wpt>
+<wpt lat="1" lon="2"><magvar>354.5</magvar></wpt>
+</gpx>`)
+	assert.Nil(t, err)
+	assert.True(t, g.Waypoints[0].MagneticVariation.Null())
+	assert.True(t, g.Waypoints[1].MagneticVariation.Null())
+	assert.Equal(t, 354.5, g.Waypoints[2].MagneticVariation.Value())
+
+	xml, err := g.ToXml(ToXmlParams{})
+	assert.Nil(t, err)
+	assert.NotContains(t, string(xml), "-5.5")
+	assert.Contains(t, string(xml), "<magvar>354.5</magvar>")
+}
+
+func TestMagneticBearing(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 355.0, MagneticBearing(5, 10))
+	assert.Equal(t, 15.0, MagneticBearing(5, -10))
+}
+
+func TestAxialDipoleDeclination(t *testing.T) {
+	t.Parallel()
+
+	model := parseTestMagneticModel(t, "  1  0  -29404.5       0.0        0.0        0.0")
+	for _, lat := range []float64{-60, 0, 45} {
+		for _, lon := range []float64{-120, 0, 90} {
+			field, err := model.Field(lat, lon, 0, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+			assert.Nil(t, err)
+			assert.InDelta(t, 0, field.Declination(), 1e-9)
+			assert.True(t, field.X > 0, "the field must point north")
+		}
+	}
+
+	equator, err := model.Field(0, 0, 0, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	expected := 29404.5 * math.Pow(wmmReferenceRadius/wmmSemiMajorAxis, 3)
+	assert.InDelta(t, expected, equator.TotalIntensity(), 1e-6)
+	assert.InDelta(t, 0, equator.Inclination(), 1e-9)
+}
+
+func TestEquatorialDipoleDeclination(t *testing.T) {
+	t.Parallel()
+
+	model := parseTestMagneticModel(t, "  1  1   -1450.7       0.0        0.0        0.0")
+	declination, err := model.Declination(0, 90, 0, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.InDelta(t, -90, declination, 1e-9)
+}
+
+func TestMagneticSecularVariation(t *testing.T) {
+	t.Parallel()
+
+	model := parseTestMagneticModel(t, "  1  0  -30000.0       0.0        0.0        0.0\n  1  1       0.0       0.0      100.0        0.0")
+	declination, err := model.Declination(0, 90, 0, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.InDelta(t, 0, declination, 1e-9)
+	declination, err = model.Declination(0, 90, 0, time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.True(t, declination > 0)
+}
+
+func TestMagneticModelValidity(t *testing.T) {
+	t.Parallel()
+
+	model := parseTestMagneticModel(t, "  1  0  -30000.0       0.0        0.0        0.0")
+	for _, outside := range []time.Time{time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)} {
+		_, err := model.Field(0, 90, 0, outside)
+		assert.NotNil(t, err)
+		_, err = model.Declination(0, 90, 0, outside)
+		assert.NotNil(t, err)
+	}
+
+	g := GPX{}
+	g.AppendWaypoint(&GPXPoint{Point: Point{Latitude: 0, Longitude: 90}, MagneticVariation: *NewNullableFloat64(10)})
+	g.AppendWaypoint(&GPXPoint{Point: Point{Latitude: 0, Longitude: 90}, Timestamp: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)})
+	assert.NotNil(t, g.FillMagneticVariation(model, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+	// Nothing changed:
+	assert.Equal(t, 10.0, g.Waypoints[0].MagneticVariation.Value())
+}
+
+func TestFillMagneticVariation(t *testing.T) {
+	t.Parallel()
+
+	model := parseTestMagneticModel(t, "  1  0  -29404.5       0.0        0.0        0.0\n  1  1   -1450.7       0.0        0.0        0.0")
+	g := GPX{}
+	g.AppendWaypoint(&GPXPoint{Point: Point{Latitude: 0, Longitude: 90}})
+	assert.Nil(t, g.FillMagneticVariation(model, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	declination, err := model.Declination(0, 90, 0, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.True(t, declination < 0)
+	assert.InDelta(t, 360+declination, g.Waypoints[0].MagneticVariation.Value(), 1e-9)
+}
+
+func TestParseInvalidMagneticModel(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseMagneticModel(strings.NewReader(""))
+	assert.NotNil(t, err)
+	_, err = ParseMagneticModel(strings.NewReader("2020.0 WMM\n  1  2  1 2 3 4\n"))
+	assert.NotNil(t, err)
+}
+
+func TestWMM2020TestValues(t *testing.T) {
+	t.Parallel()
+
+	model, err := ParseMagneticModelFile("../test_files/WMM2020.COF")
+	assert.Nil(t, err)
+	assert.Equal(t, 2020.0, model.Epoch)
+	assert.Equal(t, "WMM-2020", model.Name)
+
+	// Test values from the WMM2020 technical report
+	epoch := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		altitude, latitude, longitude float64
+		x, y, z, declination          float64
+	}{
+		{0, 80, 0, 6570.4, -146.3, 54606.0, -1.28},
+		{0, 0, 120, 39624.3, 109.9, -10932.5, 0.16},
+		{0, -80, 240, 5940.6, 15772.1, -52480.8, 69.36},
+		{100000, 80, 0, 6261.8, -185.5, 52429.1, -1.70},
+		{100000, 0, 120, 37636.7, 104.9, -10474.8, 0.16},
+		{100000, -80, 240, 5744.9, 14799.5, -49969.4, 68.78},
+	} {
+		field, err := model.Field(test.latitude, test.longitude, test.altitude, epoch)
+		assert.Nil(t, err)
+		assert.InDelta(t, test.x, field.X, 1, "%+v", test)
+		assert.InDelta(t, test.y, field.Y, 1, "%+v", test)
+		assert.InDelta(t, test.z, field.Z, 1, "%+v", test)
+		assert.InDelta(t, test.declination, field.Declination(), 0.1, "%+v", test)
+	}
+}
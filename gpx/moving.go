@@ -0,0 +1,164 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"time"
+)
+
+// MovingOptions contains the settings for detecting stops. Zero fields are
+// replaced with the values from DefaultMovingOptions.
+type MovingOptions struct {
+	// StoppedSpeedThreshold in m/s, slower (3D) intervals between points are stops
+	StoppedSpeedThreshold float64
+	// MinStopDuration, shorter stops are counted as moving
+	MinStopDuration time.Duration
+	// JitterRadius in meters. Points staying within this radius from the
+	// first one for at least MinStopDuration are a stop even if the GPS
+	// jitter makes them look faster than StoppedSpeedThreshold. Ignored if
+	// MinStopDuration is zero.
+	JitterRadius float64
+}
+
+// DefaultMovingOptions are used by MovingData, StoppedPositions and the other methods without options
+var DefaultMovingOptions = MovingOptions{
+	StoppedSpeedThreshold: defaultStoppedSpeedThreshold / 3.6,
+}
+
+func (opts MovingOptions) withDefaults() MovingOptions {
+	if opts.StoppedSpeedThreshold <= 0 {
+		opts.StoppedSpeedThreshold = DefaultMovingOptions.StoppedSpeedThreshold
+	}
+	if opts.MinStopDuration <= 0 {
+		opts.MinStopDuration = DefaultMovingOptions.MinStopDuration
+	}
+	if opts.JitterRadius <= 0 {
+		opts.JitterRadius = DefaultMovingOptions.JitterRadius
+	}
+	return opts
+}
+
+// stoppedIntervals returns true at pointNo if the interval between pointNo-1
+// and pointNo is stopped (the value for the first point is always false)
+func (seg *GPXTrackSegment) stoppedIntervals(opts MovingOptions) []bool {
+	opts = opts.withDefaults()
+	result := make([]bool, len(seg.Points))
+	for pointNo := 1; pointNo < len(seg.Points); pointNo++ {
+		previous, point := &seg.Points[pointNo-1], &seg.Points[pointNo]
+		seconds := point.Timestamp.Sub(previous.Timestamp).Seconds()
+		var speed float64
+		if seconds > 0 {
+			speed = point.Distance3D(previous) / seconds
+		}
+		result[pointNo] = speed <= opts.StoppedSpeedThreshold
+	}
+
+	if opts.MinStopDuration <= 0 {
+		return result
+	}
+
+	if opts.JitterRadius > 0 {
+		anchor := 0
+		for pointNo := 1; pointNo <= len(seg.Points); pointNo++ {
+			if pointNo < len(seg.Points) && seg.Points[pointNo].Distance2D(&seg.Points[anchor]) <= opts.JitterRadius {
+				continue
+			}
+			last := pointNo - 1
+			if last > anchor && seg.Points[last].Timestamp.Sub(seg.Points[anchor].Timestamp) >= opts.MinStopDuration {
+				for n := anchor + 1; n <= last; n++ {
+					result[n] = true
+				}
+			}
+			anchor = pointNo
+		}
+	}
+
+	// Short stops are moving:
+	for first := 1; first < len(result); first++ {
+		if !result[first] {
+			continue
+		}
+		last := first
+		for last+1 < len(result) && result[last+1] {
+			last++
+		}
+		if seg.Points[last].Timestamp.Sub(seg.Points[first-1].Timestamp) < opts.MinStopDuration {
+			for n := first; n <= last; n++ {
+				result[n] = false
+			}
+		}
+		first = last
+	}
+
+	return result
+}
+
+// StopInterval is a stop (pause) detected on a track
+type StopInterval struct {
+	// Start is the last point before and End the first point after the stop
+	Start TrackPosition
+	End   TrackPosition
+	// StartTime and EndTime are the timestamps of the Start and End points
+	StartTime time.Time
+	EndTime   time.Time
+	// Centroid is the average location of the stop points
+	Centroid Point
+}
+
+// Duration returns the stop duration in seconds
+func (si StopInterval) Duration() float64 {
+	return si.EndTime.Sub(si.StartTime).Seconds()
+}
+
+// AutoPause returns the stops in the segment
+func (seg *GPXTrackSegment) AutoPause(opts MovingOptions) []StopInterval {
+	result := make([]StopInterval, 0)
+	stopped := seg.stoppedIntervals(opts)
+	for first := 1; first < len(stopped); first++ {
+		if !stopped[first] {
+			continue
+		}
+		last := first
+		for last+1 < len(stopped) && stopped[last+1] {
+			last++
+		}
+		start, end := &seg.Points[first-1], &seg.Points[last]
+		stop := StopInterval{
+			Start:     TrackPosition{Point: start.Point, PointNo: first - 1},
+			End:       TrackPosition{Point: end.Point, PointNo: last},
+			StartTime: start.Timestamp,
+			EndTime:   end.Timestamp,
+			Centroid:  pointsCentroid(seg.Points[first-1 : last+1]),
+		}
+		result = append(result, stop)
+		first = last
+	}
+	return result
+}
+
+// AutoPause returns the stops in all track segments
+func (trk *GPXTrack) AutoPause(opts MovingOptions) []StopInterval {
+	result := make([]StopInterval, 0)
+	for segmentNo := range trk.Segments {
+		for _, stop := range trk.Segments[segmentNo].AutoPause(opts) {
+			stop.Start.SegmentNo, stop.End.SegmentNo = segmentNo, segmentNo
+			result = append(result, stop)
+		}
+	}
+	return result
+}
+
+// AutoPause returns the stops in all tracks
+func (g *GPX) AutoPause(opts MovingOptions) []StopInterval {
+	result := make([]StopInterval, 0)
+	for trackNo := range g.Tracks {
+		for _, stop := range g.Tracks[trackNo].AutoPause(opts) {
+			stop.Start.TrackNo, stop.End.TrackNo = trackNo, trackNo
+			result = append(result, stop)
+		}
+	}
+	return result
+}
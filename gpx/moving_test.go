@@ -0,0 +1,107 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoppedPositionsAndMovingDataAgree(t *testing.T) {
+	t.Parallel()
+
+	// 10s intervals, 0.5m/s (a stop with the default 1km/h threshold was counted as moving by StoppedPositions):
+	seg := newTestSegment(10, 100, 5, 5, 100)
+	stopped := seg.StoppedPositions()
+	assert.Equal(t, 0, len(stopped))
+
+	seg = newTestSegment(10, 100, 2, 2, 100)
+	stopped = seg.StoppedPositions()
+	if assert.Equal(t, 2, len(stopped)) {
+		assert.Equal(t, 2, stopped[0].PointNo)
+		assert.Equal(t, 3, stopped[1].PointNo)
+	}
+	md := seg.MovingData()
+	assert.InDelta(t, 20, md.StoppedTime, 0.001)
+	assert.InDelta(t, 20, md.MovingTime, 0.001)
+}
+
+func TestMovingOptions(t *testing.T) {
+	t.Parallel()
+
+	// Moving, 60s of GPS jitter (about 1m/s), a short 20s stop, moving:
+	seg := newTestSegment(10, 100, 10, -10, 10, -10, 10, -10, 100, 0, 0, 100)
+
+	md := seg.MovingDataWithOptions(DefaultMovingOptions)
+	assert.InDelta(t, 20, md.StoppedTime, 0.001)
+
+	opts := MovingOptions{StoppedSpeedThreshold: 0.5, MinStopDuration: 30 * time.Second}
+	md = seg.MovingDataWithOptions(opts)
+	assert.InDelta(t, 0, md.StoppedTime, 0.001)
+	assert.InDelta(t, 110, md.MovingTime, 0.001)
+
+	opts.JitterRadius = 15
+	md = seg.MovingDataWithOptions(opts)
+	assert.InDelta(t, 60, md.StoppedTime, 0.001)
+	assert.InDelta(t, 50, md.MovingTime, 0.001)
+	assert.InDelta(t, 50, seg.DurationWithOptions(opts), 0.001)
+	assert.InDelta(t, 110, seg.Duration(), 0.001)
+
+	stops := seg.AutoPause(opts)
+	if assert.Equal(t, 1, len(stops)) {
+		stop := stops[0]
+		assert.Equal(t, 1, stop.Start.PointNo)
+		assert.Equal(t, 7, stop.End.PointNo)
+		assert.Equal(t, testStartTime.Add(10*time.Second), stop.StartTime)
+		assert.Equal(t, testStartTime.Add(70*time.Second), stop.EndTime)
+		assert.InDelta(t, 60, stop.Duration(), 0.001)
+		assert.InDelta(t, 45+(4*100+3*110)/7.0/oneDegree, stop.Centroid.Latitude, 0.000001)
+		assert.InDelta(t, 13, stop.Centroid.Longitude, 0.000001)
+		assert.InDelta(t, 100, stop.Centroid.Elevation.Value(), 0.000001)
+	}
+	assert.Equal(t, 6, len(seg.StoppedPositionsWithOptions(opts)))
+}
+
+func TestAutoPauseInTracks(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 0, 0, 0, 100)
+	g := GPX{}
+	g.AppendTrack(&GPXTrack{})
+	g.AppendTrack(&GPXTrack{})
+	g.AppendSegment(&GPXTrackSegment{})
+	g.AppendSegment(&seg)
+
+	stops := g.AutoPause(MovingOptions{MinStopDuration: 20 * time.Second})
+	if assert.Equal(t, 1, len(stops)) {
+		assert.Equal(t, 1, stops[0].Start.TrackNo)
+		assert.Equal(t, 1, stops[0].Start.SegmentNo)
+		assert.Equal(t, 1, stops[0].Start.PointNo)
+		assert.Equal(t, 4, stops[0].End.PointNo)
+	}
+	assert.InDelta(t, 20, g.DurationWithOptions(MovingOptions{MinStopDuration: 20 * time.Second}), 0.001)
+	assert.Equal(t, 0, len(g.AutoPause(MovingOptions{MinStopDuration: time.Minute})))
+}
+
+func TestMovingOptionsDefaults(t *testing.T) {
+	t.Parallel()
+
+	// Crawling at 0.1 m/s for 30 seconds:
+	seg := newTestSegment(10, 100, 1, 1, 1, 100)
+
+	opts := MovingOptions{MinStopDuration: 20 * time.Second}
+	assert.Equal(t, DefaultMovingOptions.StoppedSpeedThreshold, opts.withDefaults().StoppedSpeedThreshold)
+	assert.Equal(t, 20*time.Second, opts.withDefaults().MinStopDuration)
+
+	stops := seg.AutoPause(opts)
+	if assert.Equal(t, 1, len(stops)) {
+		assert.Equal(t, 1, stops[0].Start.PointNo)
+		assert.Equal(t, 4, stops[0].End.PointNo)
+	}
+	assert.InDelta(t, 20, seg.DurationWithOptions(opts), 0.001)
+}
@@ -66,9 +66,9 @@ func (n *NullableFloat64) UnmarshalXML(d *xml.Decoder, start xml.StartElement) e
 		value, err := strconv.ParseFloat(strData, 64)
 		if err != nil {
 			n.SetNull()
-			return nil
+		} else {
+			n.SetValue(value)
 		}
-		n.SetValue(value)
 	}
 	d.Skip()
 	return nil
@@ -0,0 +1,207 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+)
+
+const gravity = 9.80665
+
+// CyclingPowerModel contains the physical parameters for estimating cycling
+// power (for rides without a power meter)
+type CyclingPowerModel struct {
+	// Mass of the rider and bike in kg
+	Mass float64
+	// CdA is the drag area in m²
+	CdA float64
+	// Crr is the rolling resistance coefficient
+	Crr float64
+	// AirDensity in kg/m³
+	AirDensity float64
+	// DrivetrainEfficiency is the part of the rider power reaching the wheel
+	DrivetrainEfficiency float64
+	// WindSpeed in m/s and WindDirection (where the wind is blowing from) in degrees, optional
+	WindSpeed     float64
+	WindDirection float64
+}
+
+// DefaultCyclingPowerModel is a road cyclist on the hoods without wind
+var DefaultCyclingPowerModel = CyclingPowerModel{
+	Mass:                 85,
+	CdA:                  0.32,
+	Crr:                  0.005,
+	AirDensity:           1.225,
+	DrivetrainEfficiency: 0.97,
+}
+
+// Power returns the rider power in watts for the speed (m/s), gradient (in
+// percent), acceleration (m/s²) and bearing (degrees). Negative values
+// (braking, coasting downhill) are returned as zero.
+func (m CyclingPowerModel) Power(speed, gradient, acceleration, bearing float64) float64 {
+	if speed <= 0 {
+		return 0
+	}
+	slope := math.Atan(gradient / 100)
+	headwind := m.WindSpeed * math.Cos(ToRad(m.WindDirection-bearing))
+	airSpeed := speed + headwind
+
+	force := m.Mass*gravity*math.Sin(slope) +
+		m.Mass*gravity*math.Cos(slope)*m.Crr +
+		0.5*m.AirDensity*m.CdA*airSpeed*math.Abs(airSpeed) +
+		m.Mass*acceleration
+
+	efficiency := m.DrivetrainEfficiency
+	if efficiency <= 0 {
+		efficiency = 1
+	}
+	return math.Max(0, force*speed/efficiency)
+}
+
+// pointSpeeds returns GPXTrackSegment.Speed for every point (null for points
+// without timestamps)
+func (seg *GPXTrackSegment) pointSpeeds() []NullableFloat64 {
+	result := make([]NullableFloat64, len(seg.Points))
+	for pointNo := range seg.Points {
+		if hasTimestamp(&seg.Points[pointNo]) {
+			result[pointNo] = *NewNullableFloat64(seg.Speed(pointNo))
+		}
+	}
+	return result
+}
+
+// EstimatePower returns the estimated cycling power (watts) for every point.
+// Points without timestamps are null. Points without elevation are
+// estimated with the gradient of the neighbouring points (or flat).
+func (seg *GPXTrackSegment) EstimatePower(model CyclingPowerModel) []NullableFloat64 {
+	result := make([]NullableFloat64, len(seg.Points))
+	speeds := seg.pointSpeeds()
+	gradients := newElevationProfile(seg.Points).pointGradients(0)
+	for pointNo := range seg.Points {
+		if speeds[pointNo].Null() {
+			continue
+		}
+		previous, next := pointNo, pointNo
+		if pointNo > 0 && speeds[pointNo-1].NotNull() {
+			previous = pointNo - 1
+		}
+		if pointNo < len(seg.Points)-1 && speeds[pointNo+1].NotNull() {
+			next = pointNo + 1
+		}
+
+		var acceleration, bearing, gradient float64
+		if seconds := seg.Points[next].Timestamp.Sub(seg.Points[previous].Timestamp).Seconds(); seconds > 0 {
+			acceleration = (speeds[next].Value() - speeds[previous].Value()) / seconds
+		}
+		if seg.Points[previous].Distance2D(&seg.Points[next]) > 0 {
+			bearing = AngleFromNorth(seg.Points[previous].Point, seg.Points[next].Point, false)
+		}
+		if gradients[pointNo].Percent.NotNull() {
+			gradient = gradients[pointNo].Percent.Value()
+		}
+		result[pointNo] = *NewNullableFloat64(model.Power(speeds[pointNo].Value(), gradient, acceleration, bearing))
+	}
+	return result
+}
+
+// WriteEstimatedPower writes the estimated cycling power into the point extensions
+func (seg *GPXTrackSegment) WriteEstimatedPower(model CyclingPowerModel) {
+	for pointNo, power := range seg.EstimatePower(model) {
+		if power.NotNull() {
+			seg.Points[pointNo].SetPower(math.Round(power.Value()))
+		}
+	}
+}
+
+// WriteEstimatedPower writes the estimated cycling power into the point extensions
+func (trk *GPXTrack) WriteEstimatedPower(model CyclingPowerModel) {
+	for segmentNo := range trk.Segments {
+		trk.Segments[segmentNo].WriteEstimatedPower(model)
+	}
+}
+
+// WriteEstimatedPower writes the estimated cycling power into the point extensions
+func (g *GPX) WriteEstimatedPower(model CyclingPowerModel) {
+	for trackNo := range g.Tracks {
+		g.Tracks[trackNo].WriteEstimatedPower(model)
+	}
+}
+
+// ----------------------------------------------------------------------------------------------------
+
+// FootActivity is walking or running
+type FootActivity int
+
+const (
+	Walking FootActivity = iota
+	Running
+)
+
+// CostOfTransport returns the metabolic energy cost of walking or running
+// in J/(kg·m) for the gradient in percent (Minetti et al. 2002). Gradients
+// are limited to ±45%.
+func (a FootActivity) CostOfTransport(gradient float64) float64 {
+	i := math.Max(-0.45, math.Min(0.45, gradient/100))
+	if a == Running {
+		return 155.4*math.Pow(i, 5) - 30.4*math.Pow(i, 4) - 43.3*math.Pow(i, 3) + 46.3*i*i + 19.5*i + 3.6
+	}
+	return 280.5*math.Pow(i, 5) - 58.7*math.Pow(i, 4) - 76.8*math.Pow(i, 3) + 51.9*i*i + 19.6*i + 2.5
+}
+
+const joulesPerKilocalorie = 4184
+
+// EstimateMetabolicPower returns the estimated metabolic power (watts) of
+// walking or running for every point (null for points without timestamps)
+func (seg *GPXTrackSegment) EstimateMetabolicPower(activity FootActivity, mass float64) []NullableFloat64 {
+	result := make([]NullableFloat64, len(seg.Points))
+	speeds := seg.pointSpeeds()
+	gradients := newElevationProfile(seg.Points).pointGradients(0)
+	for pointNo := range seg.Points {
+		if speeds[pointNo].Null() {
+			continue
+		}
+		var gradient float64
+		if gradients[pointNo].Percent.NotNull() {
+			gradient = gradients[pointNo].Percent.Value()
+		}
+		result[pointNo] = *NewNullableFloat64(activity.CostOfTransport(gradient) * mass * speeds[pointNo].Value())
+	}
+	return result
+}
+
+// EstimateEnergy returns the estimated (net metabolic) energy of walking or
+// running in kilocalories. Mass is the body mass in kg.
+func (seg *GPXTrackSegment) EstimateEnergy(activity FootActivity, mass float64) float64 {
+	profile := newElevationProfile(seg.Points)
+	var joules float64
+	for pointNo := 1; pointNo < len(seg.Points); pointNo++ {
+		distance := profile.pointDistances[pointNo] - profile.pointDistances[pointNo-1]
+		var gradient float64
+		if g := profile.gradient(profile.pointDistances[pointNo-1], profile.pointDistances[pointNo]); g.NotNull() {
+			gradient = g.Value()
+		}
+		joules += activity.CostOfTransport(gradient) * mass * distance
+	}
+	return joules / joulesPerKilocalorie
+}
+
+// EstimateEnergy returns the estimated energy of walking or running in kilocalories
+func (trk *GPXTrack) EstimateEnergy(activity FootActivity, mass float64) float64 {
+	var result float64
+	for segmentNo := range trk.Segments {
+		result += trk.Segments[segmentNo].EstimateEnergy(activity, mass)
+	}
+	return result
+}
+
+// EstimateEnergy returns the estimated energy of walking or running in kilocalories
+func (g *GPX) EstimateEnergy(activity FootActivity, mass float64) float64 {
+	var result float64
+	for trackNo := range g.Tracks {
+		result += g.Tracks[trackNo].EstimateEnergy(activity, mass)
+	}
+	return result
+}
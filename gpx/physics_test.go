@@ -0,0 +1,71 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCyclingPowerModel(t *testing.T) {
+	t.Parallel()
+
+	model := DefaultCyclingPowerModel
+	flat := model.Power(10, 0, 0, 0)
+	assert.InDelta(t, (85*gravity*0.005+0.5*1.225*0.32*100)*10/0.97, flat, 0.001)
+	assert.True(t, model.Power(10, 5, 0, 0) > flat+300)
+	assert.Equal(t, 0.0, model.Power(10, -10, 0, 0))
+	assert.Equal(t, 0.0, model.Power(0, 10, 0, 0))
+	assert.True(t, model.Power(10, 0, 0.5, 0) > flat)
+
+	model.WindSpeed, model.WindDirection = 5, 0
+	headwind := model.Power(10, 0, 0, 0)
+	tailwind := model.Power(10, 0, 0, 180)
+	assert.InDelta(t, (85*gravity*0.005+0.5*1.225*0.32*225)*10/0.97, headwind, 0.001)
+	assert.True(t, tailwind < flat)
+	assert.InDelta(t, flat, model.Power(10, 0, 0, 90), 0.001)
+}
+
+func TestEstimatePower(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 100, 100)
+	seg.Points[3].Timestamp = time.Time{}
+	powers := seg.EstimatePower(DefaultCyclingPowerModel)
+	flat := DefaultCyclingPowerModel.Power(10, 0, 0, 0)
+	assert.InDelta(t, flat, powers[0].Value(), 0.01)
+	assert.InDelta(t, flat, powers[1].Value(), 0.01)
+	assert.True(t, powers[3].Null())
+
+	g := GPX{}
+	g.AppendSegment(&seg)
+	g.WriteEstimatedPower(DefaultCyclingPowerModel)
+	power := g.Tracks[0].Segments[0].Points[2].Power()
+	assert.Equal(t, 245.0, power.Value())
+	power = g.Tracks[0].Segments[0].Points[3].Power()
+	assert.True(t, power.Null())
+}
+
+func TestEstimateEnergy(t *testing.T) {
+	t.Parallel()
+
+	assert.InDelta(t, 3.6, Running.CostOfTransport(0), 0.0001)
+	assert.InDelta(t, 2.5, Walking.CostOfTransport(0), 0.0001)
+	assert.InDelta(t, 5.968, Running.CostOfTransport(10), 0.001)
+	assert.Equal(t, Walking.CostOfTransport(45), Walking.CostOfTransport(60))
+
+	seg := newTestSegment(100, 100, 100, 100)
+	assert.InDelta(t, 3.6*70*300/4184, seg.EstimateEnergy(Running, 70), 0.01)
+	powers := seg.EstimateMetabolicPower(Walking, 70)
+	assert.InDelta(t, 2.5*70*1, powers[1].Value(), 0.01)
+
+	uphill := newTestElevationSegment(100, 100, 110, 120, 130)
+	g := GPX{}
+	g.AppendSegment(&uphill)
+	assert.InDelta(t, 5.968*70*300/4184, g.EstimateEnergy(Running, 70), 0.01)
+}
@@ -0,0 +1,168 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math/rand"
+	"time"
+)
+
+// PrivacyOptions contains the settings for hiding sensitive locations
+type PrivacyOptions struct {
+	// Zones are circles around sensitive locations (for example home), all
+	// points within them are removed and track segments are split there
+	Zones []Circle
+	// TrimDistance is the distance (in meters) removed from the start and
+	// the end of every track
+	TrimDistance float64
+	// RandomDistance is the biggest random distance (in meters) added to
+	// the zone radiuses and to the trim distance, so that the hidden
+	// location can't be guessed from where the track starts
+	RandomDistance float64
+	// StripMetadata removes the identifying metadata (see StripMetadata)
+	StripMetadata bool
+	// Random is the source of random distances (time seeded if nil)
+	Random *rand.Rand
+}
+
+// trimPoints removes the points nearer than fromStart (in meters) to the
+// track start and nearer than fromEnd to the track end (segments are joined)
+func (trk *GPXTrack) trimPoints(fromStart, fromEnd float64) {
+	joined := make([]GPXPoint, 0, trk.GetTrackPointsNo())
+	for _, segment := range trk.Segments {
+		joined = append(joined, segment.Points...)
+	}
+	distances := cumulativeDistances(joined)
+	if len(distances) == 0 {
+		return
+	}
+	length := distances[len(distances)-1]
+
+	n := 0
+	for segmentNo := range trk.Segments {
+		points := make([]GPXPoint, 0)
+		for _, point := range trk.Segments[segmentNo].Points {
+			if distances[n] >= fromStart && length-distances[n] >= fromEnd {
+				points = append(points, point)
+			}
+			n++
+		}
+		trk.Segments[segmentNo].Points = points
+	}
+}
+
+// removeInZones returns the parts of the line between points outside all zones
+func removeInZones(points []GPXPoint, zones []Circle) [][]GPXPoint {
+	result := make([][]GPXPoint, 0)
+	current := make([]GPXPoint, 0)
+	for pointNo := range points {
+		inZone := false
+		for zoneNo := range zones {
+			if zones[zoneNo].Contains(&points[pointNo]) {
+				inZone = true
+				break
+			}
+		}
+		if inZone {
+			if len(current) > 0 {
+				result = append(result, current)
+				current = make([]GPXPoint, 0)
+			}
+			continue
+		}
+		current = append(current, points[pointNo])
+	}
+	if len(current) > 0 {
+		result = append(result, current)
+	}
+	return result
+}
+
+// StripMetadata removes the author, creator, copyright and source
+// information and all non-point extensions (device and application data)
+func (g *GPX) StripMetadata() {
+	g.Creator = ""
+	g.AuthorName = ""
+	g.AuthorEmail = ""
+	g.AuthorLink = ""
+	g.AuthorLinkText = ""
+	g.AuthorLinkType = ""
+	g.Copyright = ""
+	g.Extensions = Extension{}
+	g.MetadataExtensions = Extension{}
+	for routeNo := range g.Routes {
+		g.Routes[routeNo].Source = ""
+		g.Routes[routeNo].Extensions = Extension{}
+	}
+	for trackNo := range g.Tracks {
+		g.Tracks[trackNo].Source = ""
+		g.Tracks[trackNo].Extensions = Extension{}
+		for segmentNo := range g.Tracks[trackNo].Segments {
+			g.Tracks[trackNo].Segments[segmentNo].Extensions = Extension{}
+		}
+	}
+	g.ExecuteOnAllPoints(func(point *GPXPoint) {
+		point.Source = ""
+	})
+}
+
+// ApplyPrivacy hides the sensitive locations. Points in the privacy zones
+// (waypoints, route and track points) are removed, tracks are trimmed at the
+// start and end, and tracks without points are removed.
+func (g *GPX) ApplyPrivacy(opts PrivacyOptions) {
+	random := opts.Random
+	if random == nil {
+		random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	randomDistance := func() float64 {
+		if opts.RandomDistance <= 0 {
+			return 0
+		}
+		return random.Float64() * opts.RandomDistance
+	}
+
+	zones := make([]Circle, len(opts.Zones))
+	for zoneNo, zone := range opts.Zones {
+		zones[zoneNo] = Circle{Center: zone.Center, Radius: zone.Radius + randomDistance()}
+	}
+
+	if opts.TrimDistance > 0 {
+		for trackNo := range g.Tracks {
+			g.Tracks[trackNo].trimPoints(opts.TrimDistance+randomDistance(), opts.TrimDistance+randomDistance())
+		}
+	}
+
+	if len(zones) > 0 {
+		waypoints := make([]GPXPoint, 0)
+		for _, points := range removeInZones(g.Waypoints, zones) {
+			waypoints = append(waypoints, points...)
+		}
+		g.Waypoints = waypoints
+		for routeNo := range g.Routes {
+			points := make([]GPXPoint, 0)
+			for _, part := range removeInZones(g.Routes[routeNo].Points, zones) {
+				points = append(points, part...)
+			}
+			g.Routes[routeNo].Points = points
+		}
+		for trackNo := range g.Tracks {
+			track := &g.Tracks[trackNo]
+			segments := make([]GPXTrackSegment, 0)
+			for _, segment := range track.Segments {
+				for _, points := range removeInZones(segment.Points, zones) {
+					segments = append(segments, GPXTrackSegment{Points: points, Extensions: segment.Extensions})
+				}
+			}
+			track.Segments = segments
+		}
+	}
+
+	g.RemoveEmpty()
+
+	if opts.StripMetadata {
+		g.StripMetadata()
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPrivacyGPX() *GPX {
+	seg := newTestSegment(10, repeatedDistances(100, 10)...)
+	return &GPX{
+		Creator:     "My device",
+		AuthorName:  "John Doe",
+		AuthorEmail: "john@example.com",
+		Waypoints:   []GPXPoint{seg.Points[0], seg.Points[5]},
+		Routes:      []GPXRoute{{Source: "My device", Points: seg.Points[:4]}},
+		Tracks:      []GPXTrack{{Source: "My device", Segments: []GPXTrackSegment{seg}}},
+	}
+}
+
+func TestPrivacyZones(t *testing.T) {
+	t.Parallel()
+
+	g := newTestPrivacyGPX()
+	home := g.Tracks[0].Segments[0].Points[0].Point
+	middle := g.Tracks[0].Segments[0].Points[5].Point
+
+	g.ApplyPrivacy(PrivacyOptions{Zones: []Circle{{Center: home, Radius: 250}, {Center: middle, Radius: 50}}})
+
+	assert.Equal(t, 0, len(g.Waypoints))
+	assert.Equal(t, 1, len(g.Routes[0].Points))
+	assert.Equal(t, 1, len(g.Tracks))
+	// Split at the middle zone:
+	assert.Equal(t, 2, len(g.Tracks[0].Segments))
+	assert.Equal(t, 2, len(g.Tracks[0].Segments[0].Points))
+	assert.Equal(t, 5, len(g.Tracks[0].Segments[1].Points))
+	for _, seg := range g.Tracks[0].Segments {
+		for _, point := range seg.Points {
+			assert.True(t, point.Distance2D(&home) > 250)
+		}
+	}
+	assert.Equal(t, "John Doe", g.AuthorName)
+}
+
+func TestPrivacyTrim(t *testing.T) {
+	t.Parallel()
+
+	g := newTestPrivacyGPX()
+	g.ApplyPrivacy(PrivacyOptions{TrimDistance: 150})
+	assert.Equal(t, 7, len(g.Tracks[0].Segments[0].Points))
+	assert.InDelta(t, 200, g.Tracks[0].Segments[0].Points[0].Distance2D(&newTestPrivacyGPX().Tracks[0].Segments[0].Points[0]), 0.01)
+
+	// Everything trimmed:
+	g = newTestPrivacyGPX()
+	g.ApplyPrivacy(PrivacyOptions{TrimDistance: 600})
+	assert.Equal(t, 0, len(g.Tracks))
+}
+
+func TestPrivacyRandomTrim(t *testing.T) {
+	t.Parallel()
+
+	random := rand.New(rand.NewSource(1))
+	lengths := map[int]bool{}
+	for i := 0; i < 50; i++ {
+		g := newTestPrivacyGPX()
+		g.ApplyPrivacy(PrivacyOptions{TrimDistance: 150, RandomDistance: 100, Random: random})
+		pointsNo := g.GetTrackPointsNo()
+		assert.True(t, pointsNo >= 5 && pointsNo <= 7, "%d points", pointsNo)
+		lengths[pointsNo] = true
+	}
+	assert.Equal(t, 3, len(lengths))
+}
+
+func TestStripMetadata(t *testing.T) {
+	t.Parallel()
+
+	g := newTestPrivacyGPX()
+	g.ApplyPrivacy(PrivacyOptions{StripMetadata: true})
+	assert.Equal(t, 11, g.GetTrackPointsNo())
+	assert.Equal(t, "", g.AuthorName)
+	assert.Equal(t, "", g.AuthorEmail)
+	assert.Equal(t, "", g.Routes[0].Source)
+	assert.Equal(t, "", g.Tracks[0].Source)
+
+	xml, err := g.ToXml(ToXmlParams{})
+	assert.Nil(t, err)
+	assert.NotContains(t, string(xml), "My device")
+	assert.NotContains(t, string(xml), "John")
+	assert.Contains(t, string(xml), defaultCreator)
+}
@@ -0,0 +1,226 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func hasTimestamp(point *GPXPoint) bool {
+	return point.Timestamp.Year() > 1
+}
+
+func interpolateFloat64(value1, value2, ratio float64) float64 {
+	return value1 + (value2-value1)*ratio
+}
+
+// longitudeDelta returns the (shortest) longitude difference from lon1 to
+// lon2, across the antimeridian if nearer (-180 <= result <= 180)
+func longitudeDelta(lon1, lon2 float64) float64 {
+	delta := math.Mod(lon2-lon1, 360)
+	if delta > 180 {
+		delta -= 360
+	} else if delta < -180 {
+		delta += 360
+	}
+	return delta
+}
+
+// interpolateLongitude interpolates the longitude along the shortest way
+// (across the antimeridian if nearer), the result is in [-180, 180]
+func interpolateLongitude(lon1, lon2, ratio float64) float64 {
+	result := lon1 + longitudeDelta(lon1, lon2)*ratio
+	if result > 180 {
+		result -= 360
+	} else if result < -180 {
+		result += 360
+	}
+	return result
+}
+
+func interpolateNullableFloat64(value1, value2 NullableFloat64, ratio float64) NullableFloat64 {
+	if value1.NotNull() && value2.NotNull() {
+		return *NewNullableFloat64(interpolateFloat64(value1.Value(), value2.Value(), ratio))
+	}
+	if ratio < 0.5 {
+		return value1
+	}
+	return value2
+}
+
+// interpolatePoints returns a point between point1 and point2. Ratio 0 is
+// point1 and 1 is point2. Coordinates (across the antimeridian if nearer),
+// elevation, time and numeric extension values are interpolated linearly.
+func interpolatePoints(point1, point2 *GPXPoint, ratio float64) GPXPoint {
+	if ratio <= 0 {
+		return *point1
+	}
+	if ratio >= 1 {
+		return *point2
+	}
+
+	var result GPXPoint
+	result.Latitude = interpolateFloat64(point1.Latitude, point2.Latitude, ratio)
+	result.Longitude = interpolateLongitude(point1.Longitude, point2.Longitude, ratio)
+	result.Elevation = interpolateNullableFloat64(point1.Elevation, point2.Elevation, ratio)
+	result.GeoidHeight = interpolateNullableFloat64(point1.GeoidHeight, point2.GeoidHeight, ratio)
+	if hasTimestamp(point1) && hasTimestamp(point2) {
+		delta := point2.Timestamp.Sub(point1.Timestamp)
+		result.Timestamp = point1.Timestamp.Add(time.Duration(float64(delta) * ratio))
+	}
+	result.Extensions = interpolateExtensions(point1.Extensions, point2.Extensions, ratio)
+	return result
+}
+
+func interpolateExtensions(ext1, ext2 Extension, ratio float64) Extension {
+	result := Extension{globalNsAttrs: ext1.globalNsAttrs}
+	if len(ext1.Nodes) > 0 {
+		result.Nodes = interpolateExtensionNodes(ext1.Nodes, ext2.Nodes, ratio)
+	}
+	return result
+}
+
+func interpolateExtensionNodes(nodes1, nodes2 []ExtensionNode, ratio float64) []ExtensionNode {
+	result := make([]ExtensionNode, len(nodes1))
+	for nodeNo, node := range nodes1 {
+		var other *ExtensionNode
+		if nodeNo < len(nodes2) && nodes2[nodeNo].XMLName == node.XMLName {
+			other = &nodes2[nodeNo]
+		}
+		result[nodeNo] = interpolateExtensionNode(node, other, ratio)
+	}
+	return result
+}
+
+func interpolateExtensionNode(node ExtensionNode, other *ExtensionNode, ratio float64) ExtensionNode {
+	result := ExtensionNode{XMLName: node.XMLName, Data: node.Data}
+	if len(node.Attrs) > 0 {
+		result.Attrs = append(result.Attrs, node.Attrs...)
+	}
+	var otherNodes []ExtensionNode
+	if other != nil {
+		otherNodes = other.Nodes
+		if value, ok := interpolateNumericString(node.Data, other.Data, ratio); ok {
+			result.Data = value
+		}
+	}
+	if len(node.Nodes) > 0 {
+		result.Nodes = interpolateExtensionNodes(node.Nodes, otherNodes, ratio)
+	}
+	return result
+}
+
+func interpolateNumericString(str1, str2 string, ratio float64) (string, bool) {
+	str1, str2 = strings.TrimSpace(str1), strings.TrimSpace(str2)
+	if len(str1) == 0 || len(str2) == 0 {
+		return "", false
+	}
+	value1, err := strconv.ParseFloat(str1, 64)
+	if err != nil {
+		return "", false
+	}
+	value2, err := strconv.ParseFloat(str2, 64)
+	if err != nil {
+		return "", false
+	}
+	value := interpolateFloat64(value1, value2, ratio)
+	if !strings.ContainsAny(str1+str2, ".eE") {
+		return strconv.FormatInt(int64(math.Round(value)), 10), true
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64), true
+}
+
+// ----------------------------------------------------------------------------------------------------
+
+// ResampleByTime replaces the segment points with points interpolated at
+// fixed time intervals. The first and last points are retained. Segments
+// without timestamps are not changed.
+func (seg *GPXTrackSegment) ResampleByTime(interval time.Duration) {
+	if interval <= 0 || len(seg.Points) < 2 {
+		return
+	}
+	for pointNo := range seg.Points {
+		if !hasTimestamp(&seg.Points[pointNo]) {
+			return
+		}
+	}
+
+	first, last := seg.Points[0], seg.Points[len(seg.Points)-1]
+	newPoints := []GPXPoint{first}
+	pointNo := 0
+	for t := first.Timestamp.Add(interval); t.Before(last.Timestamp); t = t.Add(interval) {
+		for pointNo < len(seg.Points)-2 && !seg.Points[pointNo+1].Timestamp.After(t) {
+			pointNo++
+		}
+		point1, point2 := &seg.Points[pointNo], &seg.Points[pointNo+1]
+		delta := point2.Timestamp.Sub(point1.Timestamp)
+		if delta <= 0 {
+			continue
+		}
+		ratio := float64(t.Sub(point1.Timestamp)) / float64(delta)
+		newPoints = append(newPoints, interpolatePoints(point1, point2, ratio))
+	}
+	newPoints = append(newPoints, last)
+
+	seg.Points = newPoints
+}
+
+// ResampleByDistance replaces the segment points with points interpolated
+// every given number of meters (2D distance along the segment). The first
+// and last points are retained.
+func (seg *GPXTrackSegment) ResampleByDistance(meters float64) {
+	if meters <= 0 || len(seg.Points) < 2 {
+		return
+	}
+
+	newPoints := []GPXPoint{seg.Points[0]}
+	var fromStart float64
+	next := meters
+	for pointNo := 1; pointNo < len(seg.Points); pointNo++ {
+		point1, point2 := &seg.Points[pointNo-1], &seg.Points[pointNo]
+		d := point2.Distance2D(point1)
+		// The tolerance prevents duplicating the last point because of rounding errors:
+		for d > 0 && fromStart+d-next > 1e-6 {
+			newPoints = append(newPoints, interpolatePoints(point1, point2, (next-fromStart)/d))
+			next += meters
+		}
+		fromStart += d
+	}
+	newPoints = append(newPoints, seg.Points[len(seg.Points)-1])
+
+	seg.Points = newPoints
+}
+
+// ResampleByTime resamples all track segments at fixed time intervals
+func (trk *GPXTrack) ResampleByTime(interval time.Duration) {
+	for segmentNo := range trk.Segments {
+		trk.Segments[segmentNo].ResampleByTime(interval)
+	}
+}
+
+// ResampleByDistance resamples all track segments at fixed distance intervals
+func (trk *GPXTrack) ResampleByDistance(meters float64) {
+	for segmentNo := range trk.Segments {
+		trk.Segments[segmentNo].ResampleByDistance(meters)
+	}
+}
+
+// ResampleByTime resamples all tracks at fixed time intervals
+func (g *GPX) ResampleByTime(interval time.Duration) {
+	for trackNo := range g.Tracks {
+		g.Tracks[trackNo].ResampleByTime(interval)
+	}
+}
+
+// ResampleByDistance resamples all tracks at fixed distance intervals
+func (g *GPX) ResampleByDistance(meters float64) {
+	for trackNo := range g.Tracks {
+		g.Tracks[trackNo].ResampleByDistance(meters)
+	}
+}
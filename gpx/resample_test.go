@@ -0,0 +1,130 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"encoding/xml"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testStartTime = time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+
+// newTestSegment creates a segment going north with one point every
+// secondsBetween seconds and with the given distances (in meters) between
+// points.
+func newTestSegment(secondsBetween int, distances ...float64) GPXTrackSegment {
+	var seg GPXTrackSegment
+	latitude := 45.0
+	t := testStartTime
+	seg.AppendPoint(&GPXPoint{Point: Point{Latitude: latitude, Longitude: 13, Elevation: *NewNullableFloat64(100)}, Timestamp: t})
+	for _, distance := range distances {
+		latitude += distance / oneDegree
+		t = t.Add(time.Duration(secondsBetween) * time.Second)
+		seg.AppendPoint(&GPXPoint{Point: Point{Latitude: latitude, Longitude: 13, Elevation: *NewNullableFloat64(100)}, Timestamp: t})
+	}
+	return seg
+}
+
+func heartRateExtension(hr string) Extension {
+	return Extension{Nodes: []ExtensionNode{{
+		XMLName: xml.Name{Space: "http://www.garmin.com/xmlschemas/TrackPointExtension/v1", Local: "TrackPointExtension"},
+		Nodes: []ExtensionNode{{
+			XMLName: xml.Name{Space: "http://www.garmin.com/xmlschemas/TrackPointExtension/v1", Local: "hr"},
+			Data:    hr,
+		}},
+	}}}
+}
+
+func TestResampleByTime(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 100, 100)
+	seg.Points[0].Extensions = heartRateExtension("100")
+	seg.Points[1].Extensions = heartRateExtension("120")
+	seg.Points[1].Elevation.SetValue(200)
+
+	seg.ResampleByTime(4 * time.Second)
+
+	assert.Equal(t, 9, len(seg.Points))
+	assert.Equal(t, testStartTime.Add(4*time.Second), seg.Points[1].Timestamp)
+	assert.Equal(t, testStartTime.Add(30*time.Second), seg.Points[8].Timestamp)
+	assert.InDelta(t, 40, seg.Points[1].Distance2D(&seg.Points[0]), 0.01)
+	assert.InDelta(t, 140, seg.Points[1].Elevation.Value(), 0.0001)
+	assert.InDelta(t, 180, seg.Points[2].Elevation.Value(), 0.0001)
+
+	hr, found := seg.Points[1].Extensions.Nodes[0].GetNode("hr")
+	assert.True(t, found)
+	assert.Equal(t, "108", hr.Data)
+}
+
+func TestResampleByTimeWithoutTimestamps(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 100)
+	seg.Points[1].Timestamp = time.Time{}
+	seg.ResampleByTime(time.Second)
+	assert.Equal(t, 3, len(seg.Points))
+}
+
+func TestResampleByDistance(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 50, 150)
+	seg.ResampleByDistance(60)
+
+	assert.Equal(t, 6, len(seg.Points))
+	for pointNo := 1; pointNo < len(seg.Points); pointNo++ {
+		assert.InDelta(t, 60, seg.Points[pointNo].Distance2D(&seg.Points[pointNo-1]), 0.01)
+	}
+	// Between 100m (10s) and 150m (20s):
+	assert.Equal(t, testStartTime.Add(14*time.Second), seg.Points[2].Timestamp)
+	assert.InDelta(t, 300, seg.Length2D(), 0.01)
+}
+
+func TestResampleAcrossAntimeridian(t *testing.T) {
+	t.Parallel()
+
+	seg := GPXTrackSegment{Points: []GPXPoint{
+		{Point: Point{Latitude: 10, Longitude: 179.9999}},
+		{Point: Point{Latitude: 10, Longitude: -179.9999}},
+	}}
+	length := seg.Length2D()
+	assert.InDelta(t, 21.9, length, 0.1)
+
+	seg.ResampleByDistance(length / 4)
+	assert.Equal(t, 5, len(seg.Points))
+	assert.InDelta(t, 179.99995, seg.Points[1].Longitude, 1e-9)
+	assert.InDelta(t, 180, math.Abs(seg.Points[2].Longitude), 1e-9)
+	assert.InDelta(t, -179.99995, seg.Points[3].Longitude, 1e-9)
+	assert.InDelta(t, length, seg.Length2D(), 0.1)
+
+	assert.Equal(t, 170.0, interpolateLongitude(-170, 150, 0.5))
+	assert.Equal(t, -175.0, interpolateLongitude(175, -165, 0.5))
+	assert.Equal(t, 15.0, interpolateLongitude(10, 20, 0.5))
+}
+
+func TestResampleKeepsSegments(t *testing.T) {
+	t.Parallel()
+
+	g := GPX{}
+	seg1 := newTestSegment(1, 10, 10)
+	seg2 := newTestSegment(1, 10, 10, 10)
+	g.AppendSegment(&seg1)
+	g.AppendSegment(&seg2)
+
+	g.ResampleByDistance(5)
+	assert.Equal(t, 2, len(g.Tracks[0].Segments))
+	assert.Equal(t, 5, len(g.Tracks[0].Segments[0].Points))
+	assert.Equal(t, 7, len(g.Tracks[0].Segments[1].Points))
+
+	g.ResampleByTime(2 * time.Second)
+	assert.Equal(t, 2, len(g.Tracks[0].Segments[0].Points))
+	assert.Equal(t, 3, len(g.Tracks[0].Segments[1].Points))
+}
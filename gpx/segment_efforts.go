@@ -0,0 +1,224 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// CourseSegment is a named reference polyline (like a Strava segment).
+// Efforts start at the first and finish at the last point.
+type CourseSegment struct {
+	Name   string
+	Points []GPXPoint
+}
+
+// SegmentMatchOptions contains the segment matching settings. Zero fields are
+// replaced with the values from DefaultSegmentMatchOptions.
+type SegmentMatchOptions struct {
+	// GateRadius is the distance (in meters) from the segment start and end
+	// within which the track must pass
+	GateRadius float64
+	// MaxDeviation is the biggest allowed distance (in meters) between the
+	// track and the segment polyline (in both directions)
+	MaxDeviation float64
+}
+
+// DefaultSegmentMatchOptions are used for zero SegmentMatchOptions fields
+var DefaultSegmentMatchOptions = SegmentMatchOptions{
+	GateRadius:   25,
+	MaxDeviation: 50,
+}
+
+func (opts SegmentMatchOptions) withDefaults() SegmentMatchOptions {
+	if opts.GateRadius <= 0 {
+		opts.GateRadius = DefaultSegmentMatchOptions.GateRadius
+	}
+	if opts.MaxDeviation <= 0 {
+		opts.MaxDeviation = DefaultSegmentMatchOptions.MaxDeviation
+	}
+	return opts
+}
+
+// SegmentEffort is a traversal of a CourseSegment
+type SegmentEffort struct {
+	Segment string
+	// FileNo is the index of the GPX in the Leaderboard input (0 otherwise)
+	FileNo int
+	// Start and End are the track points nearest to the segment start and end
+	Start, End TrackPosition
+	// StartTime and EndTime are zero if the track has no times
+	StartTime, EndTime time.Time
+	// ElapsedTime and MovingTime in seconds
+	ElapsedTime float64
+	MovingTime  float64
+	// Distance is the 2D length of the effort in meters
+	Distance float64
+}
+
+// gatePasses returns the first, last and nearest point index of every pass
+// (consecutive points within radius) through the gate
+func gatePasses(points []GPXPoint, gate GPXPoint, radius float64) [][3]int {
+	result := make([][3]int, 0)
+	inside := false
+	var nearestDistance float64
+	for pointNo := range points {
+		distance := points[pointNo].Distance2D(&gate)
+		if distance > radius {
+			inside = false
+			continue
+		}
+		if !inside {
+			result = append(result, [3]int{pointNo, pointNo, pointNo})
+			nearestDistance = distance
+			inside = true
+		}
+		last := &result[len(result)-1]
+		last[1] = pointNo
+		if distance < nearestDistance {
+			last[2], nearestDistance = pointNo, distance
+		}
+	}
+	return result
+}
+
+// followsPolyline checks that the points and the polyline are never more
+// than maxDeviation apart and that the points progress along the polyline
+// (never going back more than maxDeviation)
+func followsPolyline(points, polyline []GPXPoint, maxDeviation float64) bool {
+	pointsDistances, polylineDistances := cumulativeDistances(points), cumulativeDistances(polyline)
+	var progress float64
+	for pointNo := range points {
+		// The first location near enough which isn't behind (the polyline
+		// may pass the same place more than once):
+		along := math.Inf(1)
+		for legNo := 1; legNo < len(polyline); legNo++ {
+			d, a := distanceFromSegment(points[pointNo].Point, polyline[legNo-1], polyline[legNo])
+			if a += polylineDistances[legNo-1]; d <= maxDeviation && a >= progress-maxDeviation {
+				along = math.Min(along, a)
+			}
+		}
+		if math.IsInf(along, 1) {
+			return false
+		}
+		progress = math.Max(progress, along)
+	}
+	for pointNo := range polyline {
+		if distance, _ := nearestOnPolyline(points, pointsDistances, polyline[pointNo].Point); distance > maxDeviation {
+			return false
+		}
+	}
+	return true
+}
+
+// findEfforts returns the (start, end) point indexes of all efforts. Every
+// end gate pass is paired with the last start gate pass before it (so false
+// starts are ignored), and after an effort the search continues from its end
+// (so repeated laps of a loop are all found).
+func (cs CourseSegment) findEfforts(points []GPXPoint, opts SegmentMatchOptions) [][2]int {
+	result := make([][2]int, 0)
+	if len(cs.Points) < 2 || len(points) < 2 {
+		return result
+	}
+	starts := gatePasses(points, cs.Points[0], opts.GateRadius)
+	ends := gatePasses(points, cs.Points[len(cs.Points)-1], opts.GateRadius)
+
+	searchFrom := 0
+	for _, end := range ends {
+		// The start gate must be left before reaching the end gate (for
+		// loops the start and end gates are the same):
+		startNo := -1
+		for n, start := range starts {
+			if start[1] >= end[0] {
+				break
+			}
+			if start[2] >= searchFrom {
+				startNo = n
+			}
+		}
+		if startNo < 0 {
+			continue
+		}
+		start := starts[startNo]
+		if followsPolyline(points[start[2]:end[2]+1], cs.Points, opts.MaxDeviation) {
+			result = append(result, [2]int{start[2], end[2]})
+			searchFrom = end[2]
+		}
+	}
+	return result
+}
+
+// trackEfforts returns all traversals of the segment in the track (efforts
+// can continue across track segments)
+func (cs CourseSegment) trackEfforts(trk *GPXTrack, opts SegmentMatchOptions) []SegmentEffort {
+	points := make([]GPXPoint, 0, trk.GetTrackPointsNo())
+	positions := make([]TrackPosition, 0, trk.GetTrackPointsNo())
+	for segmentNo, seg := range trk.Segments {
+		for pointNo := range seg.Points {
+			points = append(points, seg.Points[pointNo])
+			positions = append(positions, TrackPosition{Point: seg.Points[pointNo].Point, SegmentNo: segmentNo, PointNo: pointNo})
+		}
+	}
+
+	result := make([]SegmentEffort, 0)
+	for _, effort := range cs.findEfforts(points, opts.withDefaults()) {
+		first, last := effort[0], effort[1]
+		effortPoints := GPXTrackSegment{Points: points[first : last+1]}
+		result = append(result, SegmentEffort{
+			Segment:     cs.Name,
+			Start:       positions[first],
+			End:         positions[last],
+			StartTime:   points[first].Timestamp,
+			EndTime:     points[last].Timestamp,
+			ElapsedTime: effortPoints.Duration(),
+			MovingTime:  effortPoints.MovingData().MovingTime,
+			Distance:    effortPoints.Length2D(),
+		})
+	}
+	return result
+}
+
+// SegmentEfforts returns all traversals of the segment in the track
+func (trk *GPXTrack) SegmentEfforts(cs CourseSegment, opts SegmentMatchOptions) []SegmentEffort {
+	return cs.trackEfforts(trk, opts)
+}
+
+// SegmentEfforts returns all traversals of the segment in all tracks
+func (g *GPX) SegmentEfforts(cs CourseSegment, opts SegmentMatchOptions) []SegmentEffort {
+	result := make([]SegmentEffort, 0)
+	for trackNo := range g.Tracks {
+		for _, effort := range cs.trackEfforts(&g.Tracks[trackNo], opts) {
+			effort.Start.TrackNo, effort.End.TrackNo = trackNo, trackNo
+			result = append(result, effort)
+		}
+	}
+	return result
+}
+
+// Leaderboard returns the efforts from all files ranked by elapsed time
+// (efforts without times are last). SegmentEffort.FileNo is the index of
+// the file in gpxs.
+func (cs CourseSegment) Leaderboard(gpxs []*GPX, opts SegmentMatchOptions) []SegmentEffort {
+	result := make([]SegmentEffort, 0)
+	for fileNo, g := range gpxs {
+		for _, effort := range g.SegmentEfforts(cs, opts) {
+			effort.FileNo = fileNo
+			result = append(result, effort)
+		}
+	}
+	rankTime := func(effort SegmentEffort) float64 {
+		if effort.ElapsedTime <= 0 {
+			return math.Inf(1)
+		}
+		return effort.ElapsedTime
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return rankTime(result[i]) < rankTime(result[j])
+	})
+	return result
+}
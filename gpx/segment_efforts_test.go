@@ -0,0 +1,120 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func repeatedDistances(distance float64, n int) []float64 {
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = distance
+	}
+	return result
+}
+
+func newTestSegmentGPX(seg GPXTrackSegment) *GPX {
+	return &GPX{Tracks: []GPXTrack{{Segments: []GPXTrackSegment{seg}}}}
+}
+
+func TestSegmentEfforts(t *testing.T) {
+	t.Parallel()
+
+	reference := newTestSegment(1, 100, 100, 100).Points[1:]
+	cs := CourseSegment{Name: "Climb", Points: reference}
+
+	// North, back south, and north again:
+	distances := append(repeatedDistances(20, 20), repeatedDistances(-20, 20)...)
+	distances = append(distances, repeatedDistances(20, 20)...)
+	g := newTestSegmentGPX(newTestSegment(10, distances...))
+
+	efforts := g.SegmentEfforts(cs, SegmentMatchOptions{})
+	assert.Equal(t, 2, len(efforts))
+	assert.Equal(t, "Climb", efforts[0].Segment)
+	assert.Equal(t, 5, efforts[0].Start.PointNo)
+	assert.Equal(t, 15, efforts[0].End.PointNo)
+	assert.Equal(t, 100.0, efforts[0].ElapsedTime)
+	assert.Equal(t, 100.0, efforts[0].MovingTime)
+	assert.InDelta(t, 200, efforts[0].Distance, 0.1)
+	assert.Equal(t, testStartTime.Add(50*time.Second), efforts[0].StartTime)
+	assert.Equal(t, 45, efforts[1].Start.PointNo)
+	assert.Equal(t, 55, efforts[1].End.PointNo)
+
+	// Wrong direction only:
+	backwards := newTestSegmentGPX(GPXTrackSegment{Points: reversedPoints(newTestSegment(10, repeatedDistances(20, 20)...).Points)})
+	assert.Equal(t, 0, len(backwards.SegmentEfforts(cs, SegmentMatchOptions{})))
+}
+
+func TestSegmentEffortsFalseStart(t *testing.T) {
+	t.Parallel()
+
+	cs := CourseSegment{Points: newTestSegment(1, 100, 100, 100).Points[1:]}
+
+	// Through the start gate, a U-turn back (not too far from the segment)
+	// and then the real start:
+	distances := append(repeatedDistances(20, 8), repeatedDistances(-20, 5)...)
+	distances = append(distances, repeatedDistances(20, 17)...)
+	efforts := newTestSegmentGPX(newTestSegment(10, distances...)).SegmentEfforts(cs, SegmentMatchOptions{})
+	assert.Equal(t, 1, len(efforts))
+	assert.Equal(t, 15, efforts[0].Start.PointNo)
+	assert.Equal(t, 25, efforts[0].End.PointNo)
+
+	// Going back along the segment (after the start):
+	distances = append(repeatedDistances(20, 12), repeatedDistances(-20, 5)...)
+	distances = append(distances, repeatedDistances(20, 10)...)
+	efforts = newTestSegmentGPX(newTestSegment(10, distances...)).SegmentEfforts(cs, SegmentMatchOptions{})
+	assert.Equal(t, 0, len(efforts))
+}
+
+func TestSegmentEffortsLoop(t *testing.T) {
+	t.Parallel()
+
+	cs := CourseSegment{Name: "Out and back", Points: newTestSegment(1, 200, -200).Points}
+
+	distances := []float64{}
+	for lap := 0; lap < 2; lap++ {
+		distances = append(distances, repeatedDistances(20, 10)...)
+		distances = append(distances, repeatedDistances(-20, 10)...)
+	}
+	trk := GPXTrack{Segments: []GPXTrackSegment{newTestSegment(10, distances...)}}
+
+	efforts := trk.SegmentEfforts(cs, SegmentMatchOptions{})
+	assert.Equal(t, 2, len(efforts))
+	assert.Equal(t, 0, efforts[0].Start.PointNo)
+	assert.Equal(t, 20, efforts[0].End.PointNo)
+	assert.Equal(t, 20, efforts[1].Start.PointNo)
+	assert.Equal(t, 40, efforts[1].End.PointNo)
+}
+
+func TestSegmentEffortsDeviation(t *testing.T) {
+	t.Parallel()
+
+	cs := CourseSegment{Points: newTestZigZagPoints(0, 0, 0, 0, 0)}
+	detour := newTestSegmentGPX(GPXTrackSegment{Points: newTestZigZagPoints(0, 0, 100, 0, 0)})
+
+	assert.Equal(t, 0, len(detour.SegmentEfforts(cs, SegmentMatchOptions{})))
+	assert.Equal(t, 1, len(detour.SegmentEfforts(cs, SegmentMatchOptions{MaxDeviation: 101})))
+}
+
+func TestSegmentLeaderboard(t *testing.T) {
+	t.Parallel()
+
+	cs := CourseSegment{Points: newTestSegment(1, 100, 100, 100).Points[1:]}
+	slow := newTestSegmentGPX(newTestSegment(10, repeatedDistances(20, 20)...))
+	fast := newTestSegmentGPX(newTestSegment(5, repeatedDistances(20, 20)...))
+	noTimes := newTestSegmentGPX(GPXTrackSegment{Points: newTestZigZagPoints(0, 0, 0, 0, 0)})
+
+	leaderboard := cs.Leaderboard([]*GPX{noTimes, slow, fast}, SegmentMatchOptions{})
+	assert.Equal(t, 3, len(leaderboard))
+	assert.Equal(t, 2, leaderboard[0].FileNo)
+	assert.Equal(t, 50.0, leaderboard[0].ElapsedTime)
+	assert.Equal(t, 1, leaderboard[1].FileNo)
+	assert.Equal(t, 0, leaderboard[2].FileNo)
+}
@@ -0,0 +1,224 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Sensor data extension namespaces
+const (
+	GarminTrackPointExtensionV1 NamespaceURL = "http://www.garmin.com/xmlschemas/TrackPointExtension/v1"
+	GarminTrackPointExtensionV2 NamespaceURL = "http://www.garmin.com/xmlschemas/TrackPointExtension/v2"
+	ClueTrustGPXData            NamespaceURL = "http://www.cluetrust.com/XML/GPXDATA/1/0"
+)
+
+// Extension node names (Garmin TrackPointExtension, ClueTrust gpxdata and
+// other common variants) for every sensor value. The first one is used for
+// new nodes.
+var (
+	heartRateNodeNames   = []string{"hr", "heartrate"}
+	cadenceNodeNames     = []string{"cad", "cadence"}
+	temperatureNodeNames = []string{"atemp", "temp", "temperature"}
+	powerNodeNames       = []string{"power", "PowerInWatts", "watts"}
+	speedNodeNames       = []string{"speed"}
+	courseNodeNames      = []string{"course"}
+)
+
+// extensionNode returns the first extension node (at any depth) with one of
+// the given local names
+func extensionNode(nodes []ExtensionNode, names ...string) *ExtensionNode {
+	for nodeNo := range nodes {
+		for _, name := range names {
+			if strings.EqualFold(nodes[nodeNo].LocalName(), name) {
+				return &nodes[nodeNo]
+			}
+		}
+		if node := extensionNode(nodes[nodeNo].Nodes, names...); node != nil {
+			return node
+		}
+	}
+	return nil
+}
+
+// extensionValue returns the numeric value of the first extension node
+// (at any depth) with one of the given local names
+func extensionValue(nodes []ExtensionNode, names ...string) NullableFloat64 {
+	for _, node := range nodes {
+		for _, name := range names {
+			if strings.EqualFold(node.LocalName(), name) {
+				if value, err := strconv.ParseFloat(strings.TrimSpace(node.Data), 64); err == nil {
+					return *NewNullableFloat64(value)
+				}
+			}
+		}
+		if value := extensionValue(node.Nodes, names...); value.NotNull() {
+			return value
+		}
+	}
+	return NullableFloat64{}
+}
+
+// upgradeNamespace replaces the from namespace with to in the node and its subnodes
+func upgradeNamespace(node *ExtensionNode, from, to NamespaceURL) {
+	if node.SpaceNameURL() == string(from) {
+		node.XMLName.Space = string(to)
+	}
+	for nodeNo := range node.Nodes {
+		upgradeNamespace(&node.Nodes[nodeNo], from, to)
+	}
+}
+
+// setTrackPointExtensionValue updates an existing node or adds it into the
+// (existing or new) Garmin TrackPointExtension node. An existing v1 node is
+// upgraded to v2 when a v2 only value is set.
+func (pt *GPXPoint) setTrackPointExtensionValue(value float64, namespaceURL NamespaceURL, names []string) {
+	data := strconv.FormatFloat(value, 'f', -1, 64)
+	if node := extensionNode(pt.Extensions.Nodes, names...); node != nil {
+		node.Data = data
+		return
+	}
+	if tpx, found := pt.Extensions.GetNode(AnyNamespace, "TrackPointExtension"); found {
+		if namespaceURL == GarminTrackPointExtensionV2 {
+			// v2 is a superset of v1, so a v1 node is upgraded:
+			upgradeNamespace(tpx, GarminTrackPointExtensionV1, GarminTrackPointExtensionV2)
+		}
+		tpx.GetOrCreateNode(names[0]).Data = data
+		return
+	}
+	pt.Extensions.GetOrCreateNode(namespaceURL, "TrackPointExtension", names[0]).Data = data
+}
+
+// HeartRate returns the heart rate (bpm) from the point extensions
+func (pt *GPXPoint) HeartRate() NullableFloat64 {
+	return extensionValue(pt.Extensions.Nodes, heartRateNodeNames...)
+}
+
+// SetHeartRate sets the heart rate (bpm) in the point extensions. New values
+// are added in the Garmin TrackPointExtension, register its namespace with
+// GPX.RegisterNamespace to write it with a prefix.
+func (pt *GPXPoint) SetHeartRate(bpm float64) {
+	pt.setTrackPointExtensionValue(bpm, GarminTrackPointExtensionV1, heartRateNodeNames)
+}
+
+// Cadence returns the cadence (rpm) from the point extensions
+func (pt *GPXPoint) Cadence() NullableFloat64 {
+	return extensionValue(pt.Extensions.Nodes, cadenceNodeNames...)
+}
+
+// SetCadence sets the cadence (rpm) in the point extensions
+func (pt *GPXPoint) SetCadence(rpm float64) {
+	pt.setTrackPointExtensionValue(rpm, GarminTrackPointExtensionV1, cadenceNodeNames)
+}
+
+// Temperature returns the (air) temperature in °C from the point extensions
+func (pt *GPXPoint) Temperature() NullableFloat64 {
+	return extensionValue(pt.Extensions.Nodes, temperatureNodeNames...)
+}
+
+// SetTemperature sets the air temperature in °C in the point extensions
+func (pt *GPXPoint) SetTemperature(celsius float64) {
+	pt.setTrackPointExtensionValue(celsius, GarminTrackPointExtensionV1, temperatureNodeNames)
+}
+
+// Power returns the power (watts) from the point extensions
+func (pt *GPXPoint) Power() NullableFloat64 {
+	return extensionValue(pt.Extensions.Nodes, powerNodeNames...)
+}
+
+// SetPower sets the power (watts) in the point extensions. New values are
+// added as a <power> node without namespace (as most applications do).
+func (pt *GPXPoint) SetPower(watts float64) {
+	data := strconv.FormatFloat(watts, 'f', -1, 64)
+	if node := extensionNode(pt.Extensions.Nodes, powerNodeNames...); node != nil {
+		node.Data = data
+		return
+	}
+	pt.Extensions.GetOrCreateNode(NoNamespace, powerNodeNames[0]).Data = data
+}
+
+// Speed returns the speed (m/s) measured by the device from the point extensions
+func (pt *GPXPoint) Speed() NullableFloat64 {
+	return extensionValue(pt.Extensions.Nodes, speedNodeNames...)
+}
+
+// SetSpeed sets the speed (m/s) in the point extensions (Garmin TrackPointExtension v2)
+func (pt *GPXPoint) SetSpeed(speed float64) {
+	pt.setTrackPointExtensionValue(speed, GarminTrackPointExtensionV2, speedNodeNames)
+}
+
+// Course returns the course (degrees) measured by the device from the point extensions
+func (pt *GPXPoint) Course() NullableFloat64 {
+	return extensionValue(pt.Extensions.Nodes, courseNodeNames...)
+}
+
+// SetCourse sets the course (degrees) in the point extensions (Garmin TrackPointExtension v2)
+func (pt *GPXPoint) SetCourse(degrees float64) {
+	pt.setTrackPointExtensionValue(degrees, GarminTrackPointExtensionV2, courseNodeNames)
+}
+
+// ----------------------------------------------------------------------------------------------------
+
+// SensorStats contains the sensor data statistics. Averages are time
+// weighted (or simple averages if there are no times). Null if there is no
+// data.
+type SensorStats struct {
+	AverageHeartRate NullableFloat64
+	MaxHeartRate     NullableFloat64
+	AverageCadence   NullableFloat64
+	MaxCadence       NullableFloat64
+	AveragePower     NullableFloat64
+	MaxPower         NullableFloat64
+}
+
+func sensorAverageAndMax(segments []GPXTrackSegment, value func(*GPXPoint) NullableFloat64) (NullableFloat64, NullableFloat64) {
+	var sum float64
+	var count int
+	max := math.Inf(-1)
+	for segmentNo := range segments {
+		for pointNo := range segments[segmentNo].Points {
+			v := value(&segments[segmentNo].Points[pointNo])
+			if v.NotNull() {
+				sum += v.Value()
+				max = math.Max(max, v.Value())
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return NullableFloat64{}, NullableFloat64{}
+	}
+	average := timeWeightedAverage(segments, value)
+	if average.Null() {
+		average = *NewNullableFloat64(sum / float64(count))
+	}
+	return average, *NewNullableFloat64(max)
+}
+
+func sensorStats(segments []GPXTrackSegment) SensorStats {
+	var result SensorStats
+	result.AverageHeartRate, result.MaxHeartRate = sensorAverageAndMax(segments, (*GPXPoint).HeartRate)
+	result.AverageCadence, result.MaxCadence = sensorAverageAndMax(segments, (*GPXPoint).Cadence)
+	result.AveragePower, result.MaxPower = sensorAverageAndMax(segments, (*GPXPoint).Power)
+	return result
+}
+
+// SensorStats returns the heart rate, cadence and power statistics of a GPX segment
+func (seg *GPXTrackSegment) SensorStats() SensorStats {
+	return sensorStats([]GPXTrackSegment{*seg})
+}
+
+// SensorStats returns the heart rate, cadence and power statistics of a GPX track
+func (trk *GPXTrack) SensorStats() SensorStats {
+	return sensorStats(trk.Segments)
+}
+
+// SensorStats returns the heart rate, cadence and power statistics of all tracks
+func (g *GPX) SensorStats() SensorStats {
+	return sensorStats(g.allSegments())
+}
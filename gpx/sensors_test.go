@@ -0,0 +1,117 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSensorGetters(t *testing.T) {
+	t.Parallel()
+
+	g, err := ParseFile("../test_files/gpx_with_garmin_extension.gpx")
+	assert.Nil(t, err)
+	hr := g.Waypoints[0].HeartRate()
+	assert.Equal(t, 171.0, hr.Value())
+	cadence := g.Waypoints[0].Cadence()
+	assert.True(t, cadence.Null())
+
+	g, err = ParseString(`<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1"
+  xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v2"
+  xmlns:gpxdata="http://www.cluetrust.com/XML/GPXDATA/1/0">
+<trk><trkseg>
+<trkpt lat="45" lon="13"><time>2014-01-01T00:00:00Z</time><extensions>
+  <power>250</power>
+  <gpxtpx:TrackPointExtension><gpxtpx:atemp>21.5</gpxtpx:atemp><gpxtpx:hr>120</gpxtpx:hr><gpxtpx:cad>80</gpxtpx:cad><gpxtpx:speed>5.5</gpxtpx:speed><gpxtpx:course>270</gpxtpx:course></gpxtpx:TrackPointExtension>
+</extensions></trkpt>
+<trkpt lat="45.001" lon="13"><time>2014-01-01T00:00:10Z</time><extensions>
+  <gpxdata:hr>140</gpxdata:hr><gpxdata:cadence>90</gpxdata:cadence><gpxdata:temp>20</gpxdata:temp>
+</extensions></trkpt>
+</trkseg></trk>
+</gpx>`)
+	assert.Nil(t, err)
+	point1, point2 := &g.Tracks[0].Segments[0].Points[0], &g.Tracks[0].Segments[0].Points[1]
+	values := func(point *GPXPoint) []NullableFloat64 {
+		return []NullableFloat64{point.HeartRate(), point.Cadence(), point.Temperature(), point.Power(), point.Speed(), point.Course()}
+	}
+	assert.Equal(t, []NullableFloat64{
+		*NewNullableFloat64(120), *NewNullableFloat64(80), *NewNullableFloat64(21.5), *NewNullableFloat64(250), *NewNullableFloat64(5.5), *NewNullableFloat64(270),
+	}, values(point1))
+	assert.Equal(t, []NullableFloat64{
+		*NewNullableFloat64(140), *NewNullableFloat64(90), *NewNullableFloat64(20), {}, {}, {},
+	}, values(point2))
+
+	// Water temperature is not the air temperature:
+	point1.Extensions.Nodes[1].Nodes[0].XMLName.Local = "wtemp"
+	temperature := point1.Temperature()
+	assert.True(t, temperature.Null())
+
+	stats := g.SensorStats()
+	assert.Equal(t, 130.0, stats.AverageHeartRate.Value())
+	assert.Equal(t, 140.0, stats.MaxHeartRate.Value())
+	assert.Equal(t, 85.0, stats.AverageCadence.Value())
+	assert.Equal(t, 90.0, stats.MaxCadence.Value())
+	assert.Equal(t, 250.0, stats.AveragePower.Value())
+	assert.Equal(t, 250.0, stats.MaxPower.Value())
+}
+
+func TestSensorSetters(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(10, 100, 100)
+	seg.Points[0].Extensions = heartRateExtension("100")
+	seg.Points[0].SetHeartRate(110)
+	seg.Points[0].SetCadence(85)
+	seg.Points[1].SetHeartRate(150)
+	seg.Points[1].SetSpeed(10)
+	seg.Points[1].SetPower(300.5)
+	// Points without timestamps are ignored in time weighted averages:
+	seg.Points[2].Timestamp = time.Time{}
+	seg.Points[2].SetHeartRate(170)
+	seg.Points[2].SetTemperature(-3)
+	seg.Points[2].SetCourse(90)
+
+	assert.Equal(t, 1, len(seg.Points[0].Extensions.Nodes))
+	assert.Equal(t, 2, len(seg.Points[0].Extensions.Nodes[0].Nodes))
+	// The v1 TrackPointExtension is reused (and upgraded to v2) for speed:
+	assert.Equal(t, 2, len(seg.Points[1].Extensions.Nodes))
+	assert.Equal(t, string(GarminTrackPointExtensionV2), seg.Points[1].Extensions.Nodes[0].SpaceNameURL())
+	assert.Equal(t, 2, len(seg.Points[1].Extensions.Nodes[0].Nodes))
+	assert.Equal(t, string(GarminTrackPointExtensionV1), seg.Points[0].Extensions.Nodes[0].SpaceNameURL())
+
+	g := GPX{}
+	g.RegisterNamespace("gpxtpx", string(GarminTrackPointExtensionV1))
+	g.RegisterNamespace("gpxtpx2", string(GarminTrackPointExtensionV2))
+	g.AppendSegment(&seg)
+	xml, err := g.ToXml(ToXmlParams{Version: "1.1"})
+	assert.Nil(t, err)
+	assert.Contains(t, string(xml), "<gpxtpx:hr>110</gpxtpx:hr>")
+	assert.Contains(t, string(xml), "<gpxtpx2:hr>150</gpxtpx2:hr>")
+	assert.Contains(t, string(xml), "<gpxtpx2:speed>10</gpxtpx2:speed>")
+	assert.Contains(t, string(xml), "<power>300.5</power>")
+
+	reparsed, err := ParseBytes(xml)
+	assert.Nil(t, err)
+	points := reparsed.Tracks[0].Segments[0].Points
+	hr, cadence := points[0].HeartRate(), points[0].Cadence()
+	assert.Equal(t, 110.0, hr.Value())
+	assert.Equal(t, 85.0, cadence.Value())
+	hr, speed, power := points[1].HeartRate(), points[1].Speed(), points[1].Power()
+	assert.Equal(t, 150.0, hr.Value())
+	assert.Equal(t, 10.0, speed.Value())
+	assert.Equal(t, 300.5, power.Value())
+	temperature, course := points[2].Temperature(), points[2].Course()
+	assert.Equal(t, -3.0, temperature.Value())
+	assert.Equal(t, 90.0, course.Value())
+
+	stats := seg.SensorStats()
+	assert.Equal(t, 130.0, stats.AverageHeartRate.Value())
+	assert.Equal(t, 170.0, stats.MaxHeartRate.Value())
+}
@@ -0,0 +1,183 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+)
+
+// Similarity metrics between two point sequences (for example
+// GPXTrackSegment.Points or GPXRoute.Points). All distances are 2D, in meters.
+
+// FrechetDistance returns the discrete Fréchet distance, the shortest
+// "leash" needed to walk both sequences in order (without going back).
+// Returns +Inf if one of the sequences is empty.
+func FrechetDistance(points1, points2 []GPXPoint) float64 {
+	if len(points1) == 0 || len(points2) == 0 {
+		return math.Inf(1)
+	}
+	previous, current := make([]float64, len(points2)), make([]float64, len(points2))
+	for i := range points1 {
+		for j := range points2 {
+			d := points1[i].Distance2D(&points2[j])
+			switch {
+			case i == 0 && j == 0:
+				current[j] = d
+			case i == 0:
+				current[j] = math.Max(current[j-1], d)
+			case j == 0:
+				current[j] = math.Max(previous[j], d)
+			default:
+				current[j] = math.Max(math.Min(math.Min(previous[j], current[j-1]), previous[j-1]), d)
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(points2)-1]
+}
+
+// FrechetDistanceWithin returns true if the discrete Fréchet distance is not
+// bigger than threshold. It stops as soon as the threshold can't be met, so
+// it is faster than FrechetDistance for comparing many (different) tracks.
+func FrechetDistanceWithin(points1, points2 []GPXPoint, threshold float64) bool {
+	if len(points1) == 0 || len(points2) == 0 {
+		return false
+	}
+	if points1[0].Distance2D(&points2[0]) > threshold || points1[len(points1)-1].Distance2D(&points2[len(points2)-1]) > threshold {
+		return false
+	}
+	previous, current := make([]bool, len(points2)), make([]bool, len(points2))
+	for i := range points1 {
+		anyReachable := false
+		for j := range points2 {
+			var reachable bool
+			switch {
+			case i == 0 && j == 0:
+				reachable = true
+			case i == 0:
+				reachable = current[j-1]
+			case j == 0:
+				reachable = previous[j]
+			default:
+				reachable = previous[j] || current[j-1] || previous[j-1]
+			}
+			current[j] = reachable && points1[i].Distance2D(&points2[j]) <= threshold
+			anyReachable = anyReachable || current[j]
+		}
+		if !anyReachable {
+			return false
+		}
+		previous, current = current, previous
+	}
+	return previous[len(points2)-1]
+}
+
+// directedHausdorff returns the biggest distance from a point in points1 to
+// the nearest point in points2. It returns early (with a value bigger than
+// threshold) when the distance exceeds threshold.
+func directedHausdorff(points1, points2 []GPXPoint, threshold float64) float64 {
+	var result float64
+	for i := range points1 {
+		nearest := math.Inf(1)
+		for j := range points2 {
+			if d := points1[i].Distance2D(&points2[j]); d < nearest {
+				nearest = d
+				if nearest <= result {
+					// This point doesn't change the result
+					break
+				}
+			}
+		}
+		result = math.Max(result, nearest)
+		if result > threshold {
+			return result
+		}
+	}
+	return result
+}
+
+// HausdorffDistance returns the biggest distance from a point in one
+// sequence to the nearest point in the other (the order of points doesn't
+// matter). Returns +Inf if one of the sequences is empty.
+func HausdorffDistance(points1, points2 []GPXPoint) float64 {
+	if len(points1) == 0 || len(points2) == 0 {
+		return math.Inf(1)
+	}
+	return math.Max(directedHausdorff(points1, points2, math.Inf(1)), directedHausdorff(points2, points1, math.Inf(1)))
+}
+
+// HausdorffDistanceWithin returns true if the Hausdorff distance is not
+// bigger than threshold (stops at the first point too far away).
+func HausdorffDistanceWithin(points1, points2 []GPXPoint, threshold float64) bool {
+	if len(points1) == 0 || len(points2) == 0 {
+		return false
+	}
+	return directedHausdorff(points1, points2, threshold) <= threshold && directedHausdorff(points2, points1, threshold) <= threshold
+}
+
+// dtw returns the dynamic time warping cost and the warping path length. If
+// the cost exceeds maxCost it returns early with +Inf.
+func dtw(points1, points2 []GPXPoint, maxCost float64) (float64, int) {
+	if len(points1) == 0 || len(points2) == 0 {
+		return math.Inf(1), 0
+	}
+	previous, current := make([]float64, len(points2)), make([]float64, len(points2))
+	previousSteps, currentSteps := make([]int, len(points2)), make([]int, len(points2))
+	for i := range points1 {
+		rowMin := math.Inf(1)
+		for j := range points2 {
+			d := points1[i].Distance2D(&points2[j])
+			switch {
+			case i == 0 && j == 0:
+				current[j], currentSteps[j] = d, 1
+			case i == 0:
+				current[j], currentSteps[j] = current[j-1]+d, currentSteps[j-1]+1
+			case j == 0:
+				current[j], currentSteps[j] = previous[j]+d, previousSteps[j]+1
+			default:
+				best, steps := previous[j-1], previousSteps[j-1]
+				if previous[j] < best {
+					best, steps = previous[j], previousSteps[j]
+				}
+				if current[j-1] < best {
+					best, steps = current[j-1], currentSteps[j-1]
+				}
+				current[j], currentSteps[j] = best+d, steps+1
+			}
+			rowMin = math.Min(rowMin, current[j])
+		}
+		if rowMin > maxCost {
+			return math.Inf(1), 0
+		}
+		previous, current = current, previous
+		previousSteps, currentSteps = currentSteps, previousSteps
+	}
+	return previous[len(points2)-1], previousSteps[len(points2)-1]
+}
+
+// DTWDistance returns the dynamic time warping score, the sum of distances
+// between the matched points divided by the number of matches (so it is
+// the average distance between the sequences in meters). Returns +Inf if
+// one of the sequences is empty.
+func DTWDistance(points1, points2 []GPXPoint) float64 {
+	cost, steps := dtw(points1, points2, math.Inf(1))
+	if steps == 0 {
+		return math.Inf(1)
+	}
+	return cost / float64(steps)
+}
+
+// DTWDistanceWithin returns true if DTWDistance is not bigger than
+// threshold. A warping path has at most len(points1)+len(points2)-1
+// matches, so it stops as soon as every path costs more than threshold
+// times that.
+func DTWDistanceWithin(points1, points2 []GPXPoint, threshold float64) bool {
+	cost, steps := dtw(points1, points2, threshold*float64(len(points1)+len(points2)-1))
+	if steps == 0 {
+		return false
+	}
+	return cost/float64(steps) <= threshold
+}
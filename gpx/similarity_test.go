@@ -0,0 +1,100 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func reversedPoints(points []GPXPoint) []GPXPoint {
+	result := make([]GPXPoint, len(points))
+	for pointNo := range points {
+		result[len(points)-1-pointNo] = points[pointNo]
+	}
+	return result
+}
+
+func TestFrechetDistance(t *testing.T) {
+	t.Parallel()
+
+	points := newTestZigZagPoints(0, 0, 0, 0, 0)
+	shifted := newTestZigZagPoints(10, 10, 10, 10, 10)
+
+	assert.Equal(t, 0.0, FrechetDistance(points, points))
+	assert.InDelta(t, 10, FrechetDistance(points, shifted), 0.1)
+	assert.InDelta(t, 10, FrechetDistance(shifted, points), 0.1)
+	// Same points in the opposite direction:
+	assert.InDelta(t, 400, FrechetDistance(points, reversedPoints(points)), 1)
+	// Discrete Fréchet only matches points, so the middle point is 200m from both ends:
+	assert.InDelta(t, 200, FrechetDistance(points, []GPXPoint{points[0], points[4]}), 0.1)
+	assert.True(t, math.IsInf(FrechetDistance(points, nil), 1))
+
+	assert.True(t, FrechetDistanceWithin(points, shifted, 11))
+	assert.False(t, FrechetDistanceWithin(points, shifted, 9))
+	assert.False(t, FrechetDistanceWithin(points, reversedPoints(points), 300))
+	assert.True(t, FrechetDistanceWithin(points, reversedPoints(points), 401))
+	assert.False(t, FrechetDistanceWithin(points, nil, 1000))
+}
+
+func TestHausdorffDistance(t *testing.T) {
+	t.Parallel()
+
+	points := newTestZigZagPoints(0, 0, 0, 0, 0)
+	detour := newTestZigZagPoints(0, 0, 50, 0, 0)
+
+	assert.Equal(t, 0.0, HausdorffDistance(points, reversedPoints(points)))
+	assert.InDelta(t, 50, HausdorffDistance(points, detour), 0.1)
+	assert.InDelta(t, 50, HausdorffDistance(detour, points), 0.1)
+	// Only the first two points, but the rest of the line is far away:
+	assert.InDelta(t, 300, HausdorffDistance(points, points[:2]), 1)
+	assert.True(t, math.IsInf(HausdorffDistance(nil, points), 1))
+
+	assert.True(t, HausdorffDistanceWithin(points, detour, 51))
+	assert.False(t, HausdorffDistanceWithin(points, detour, 49))
+	assert.False(t, HausdorffDistanceWithin(points, points[:2], 299))
+}
+
+func TestDTWDistance(t *testing.T) {
+	t.Parallel()
+
+	points := newTestZigZagPoints(0, 0, 0, 0, 0)
+	shifted := newTestZigZagPoints(10, 10, 10, 10, 10)
+	detour := newTestZigZagPoints(0, 0, 50, 0, 0)
+
+	assert.Equal(t, 0.0, DTWDistance(points, points))
+	assert.InDelta(t, 10, DTWDistance(points, shifted), 0.1)
+	assert.InDelta(t, 10, DTWDistance(points, detour), 0.1)
+	assert.True(t, math.IsInf(DTWDistance(points, nil), 1))
+
+	assert.True(t, DTWDistanceWithin(points, shifted, 11))
+	assert.False(t, DTWDistanceWithin(points, shifted, 9))
+	assert.False(t, DTWDistanceWithin(points, reversedPoints(points), 100))
+	assert.False(t, DTWDistanceWithin(points, nil, 1000))
+}
+
+func TestDTWDistanceWithinAgreesWithDTWDistance(t *testing.T) {
+	t.Parallel()
+
+	points := newTestZigZagPoints(0, 0, 0, 0, 0)
+	for _, other := range [][]GPXPoint{
+		points,
+		newTestZigZagPoints(10, 10, 10, 10, 10),
+		newTestZigZagPoints(0, 0, 50, 0, 0),
+		newTestZigZagPoints(0, 30, 60, 30),
+		reversedPoints(points),
+		points[:2],
+	} {
+		distance := DTWDistance(points, other)
+		for _, threshold := range []float64{0, 1, 5, 9, 10, 11, 20, 50, 100, 200, 500} {
+			assert.Equal(t, distance <= threshold, DTWDistanceWithin(points, other, threshold), "distance=%f threshold=%f", distance, threshold)
+			assert.Equal(t, distance <= threshold, DTWDistanceWithin(other, points, threshold), "distance=%f threshold=%f", distance, threshold)
+		}
+		assert.True(t, DTWDistanceWithin(points, other, distance))
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"sort"
+)
+
+// IndexedPointType is the kind of point in a SpatialIndex
+type IndexedPointType int
+
+const (
+	IndexedWaypoint IndexedPointType = iota
+	IndexedRoutePoint
+	IndexedTrackPoint
+)
+
+// DefaultSpatialIndexCellSize is the grid cell size (in meters) used if the
+// cell size is not positive
+const DefaultSpatialIndexCellSize = 100.0
+
+// IndexedPoint is a spatial index query result. For waypoints only PointNo
+// is set (the waypoint index), for route points TrackNo is the route index.
+type IndexedPoint struct {
+	TrackPosition
+	Type IndexedPointType
+	// Distance is the 2D distance (in meters) from the query location (zero for bounds queries)
+	Distance float64
+}
+
+type gridCell struct {
+	lat, lon int
+}
+
+// SpatialIndex is a grid index of GPX points for fast nearest, radius and
+// bounds queries. The index is not updated when the GPX changes.
+type SpatialIndex struct {
+	// cellSize in degrees (the same for latitude and longitude)
+	cellSize float64
+	points   []IndexedPoint
+	cells    map[gridCell][]int
+}
+
+// NewSpatialIndex indexes the GPX points of the given types (all if no
+// types are given). cellSize is in meters, it should be about the typical
+// query radius.
+func NewSpatialIndex(g *GPX, cellSize float64, types ...IndexedPointType) *SpatialIndex {
+	if cellSize <= 0 {
+		cellSize = DefaultSpatialIndexCellSize
+	}
+	si := &SpatialIndex{
+		cellSize: cellSize / oneDegree,
+		points:   make([]IndexedPoint, 0),
+		cells:    map[gridCell][]int{},
+	}
+	indexed := func(t IndexedPointType) bool {
+		if len(types) == 0 {
+			return true
+		}
+		for _, typ := range types {
+			if typ == t {
+				return true
+			}
+		}
+		return false
+	}
+	if indexed(IndexedWaypoint) {
+		for pointNo, point := range g.Waypoints {
+			si.add(IndexedPoint{TrackPosition: TrackPosition{Point: point.Point, PointNo: pointNo}, Type: IndexedWaypoint})
+		}
+	}
+	if indexed(IndexedRoutePoint) {
+		for routeNo, route := range g.Routes {
+			for pointNo, point := range route.Points {
+				si.add(IndexedPoint{TrackPosition: TrackPosition{Point: point.Point, TrackNo: routeNo, PointNo: pointNo}, Type: IndexedRoutePoint})
+			}
+		}
+	}
+	if indexed(IndexedTrackPoint) {
+		for trackNo, track := range g.Tracks {
+			for segmentNo, segment := range track.Segments {
+				for pointNo, point := range segment.Points {
+					si.add(IndexedPoint{TrackPosition: TrackPosition{Point: point.Point, TrackNo: trackNo, SegmentNo: segmentNo, PointNo: pointNo}, Type: IndexedTrackPoint})
+				}
+			}
+		}
+	}
+	return si
+}
+
+func (si *SpatialIndex) cell(latitude, longitude float64) gridCell {
+	return gridCell{lat: int(math.Floor(latitude / si.cellSize)), lon: int(math.Floor(longitude / si.cellSize))}
+}
+
+func (si *SpatialIndex) add(point IndexedPoint) {
+	cell := si.cell(point.Latitude, point.Longitude)
+	si.cells[cell] = append(si.cells[cell], len(si.points))
+	si.points = append(si.points, point)
+}
+
+// Len returns the number of indexed points
+func (si *SpatialIndex) Len() int {
+	return len(si.points)
+}
+
+// candidates returns the indexes of points in cells intersecting the bounds.
+// Longitudes beyond ±180 continue across the antimeridian.
+func (si *SpatialIndex) candidates(bounds GpxBounds) []int {
+	if bounds.MaxLongitude-bounds.MinLongitude >= 360 {
+		bounds.MinLongitude, bounds.MaxLongitude = -180, 180
+	}
+	west, east := bounds, bounds
+	switch {
+	case bounds.MinLongitude < -180:
+		west.MinLongitude, west.MaxLongitude = bounds.MinLongitude+360, 180
+		east.MinLongitude = -180
+		return append(si.cellCandidates(west), si.cellCandidates(east)...)
+	case bounds.MaxLongitude > 180:
+		west.MaxLongitude = 180
+		east.MinLongitude, east.MaxLongitude = -180, bounds.MaxLongitude-360
+		return append(si.cellCandidates(west), si.cellCandidates(east)...)
+	}
+	return si.cellCandidates(bounds)
+}
+
+// cellCandidates returns the indexes of points in cells intersecting the
+// bounds (without crossing the antimeridian)
+func (si *SpatialIndex) cellCandidates(bounds GpxBounds) []int {
+	from := si.cell(bounds.MinLatitude, bounds.MinLongitude)
+	to := si.cell(bounds.MaxLatitude, bounds.MaxLongitude)
+	result := make([]int, 0)
+	if cellsNo := float64(to.lat-from.lat+1) * float64(to.lon-from.lon+1); cellsNo > float64(len(si.cells)) {
+		// Bigger than the whole index, faster to check every cell
+		for cell, pointNos := range si.cells {
+			if cell.lat >= from.lat && cell.lat <= to.lat && cell.lon >= from.lon && cell.lon <= to.lon {
+				result = append(result, pointNos...)
+			}
+		}
+		return result
+	}
+	for lat := from.lat; lat <= to.lat; lat++ {
+		for lon := from.lon; lon <= to.lon; lon++ {
+			result = append(result, si.cells[gridCell{lat: lat, lon: lon}]...)
+		}
+	}
+	return result
+}
+
+// sortedResults returns the points sorted by index (the GPX order)
+func (si *SpatialIndex) sortedResults(pointNos []int) []IndexedPoint {
+	sort.Ints(pointNos)
+	result := make([]IndexedPoint, len(pointNos))
+	for n, pointNo := range pointNos {
+		result[n] = si.points[pointNo]
+	}
+	return result
+}
+
+// InBounds returns the points within the bounds (in the GPX order)
+func (si *SpatialIndex) InBounds(bounds GpxBounds) []IndexedPoint {
+	pointNos := make([]int, 0)
+	for _, pointNo := range si.candidates(bounds) {
+		point := si.points[pointNo]
+		if point.Latitude >= bounds.MinLatitude && point.Latitude <= bounds.MaxLatitude &&
+			point.Longitude >= bounds.MinLongitude && point.Longitude <= bounds.MaxLongitude {
+			pointNos = append(pointNos, pointNo)
+		}
+	}
+	return si.sortedResults(pointNos)
+}
+
+// WithinRadius returns the points within radius meters from the location,
+// the nearest first
+func (si *SpatialIndex) WithinRadius(location Location, radius float64) []IndexedPoint {
+	latitude, longitude := location.GetLatitude(), location.GetLongitude()
+	deltaLat := math.Min(180, radius/oneDegree)
+	bounds := GpxBounds{
+		MinLatitude:  latitude - deltaLat,
+		MaxLatitude:  latitude + deltaLat,
+		MinLongitude: -180,
+		MaxLongitude: 180,
+	}
+	if cos := math.Cos(ToRad(math.Min(90, math.Abs(latitude)+deltaLat))); cos > 0 {
+		// Otherwise all longitudes
+		if deltaLon := deltaLat / cos; deltaLon < 180 {
+			bounds.MinLongitude, bounds.MaxLongitude = longitude-deltaLon, longitude+deltaLon
+		}
+	}
+
+	result := make([]IndexedPoint, 0)
+	for _, pointNo := range si.candidates(bounds) {
+		point := si.points[pointNo]
+		if distance := point.Distance2D(location); distance <= radius {
+			point.Distance = distance
+			result = append(result, point)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Distance == result[j].Distance {
+			return indexedPointLess(result[i], result[j])
+		}
+		return result[i].Distance < result[j].Distance
+	})
+	return result
+}
+
+// indexedPointLess orders points by the GPX order (waypoints, routes, tracks)
+func indexedPointLess(p1, p2 IndexedPoint) bool {
+	if p1.Type != p2.Type {
+		return p1.Type < p2.Type
+	}
+	if p1.TrackNo != p2.TrackNo {
+		return p1.TrackNo < p2.TrackNo
+	}
+	if p1.SegmentNo != p2.SegmentNo {
+		return p1.SegmentNo < p2.SegmentNo
+	}
+	return p1.PointNo < p2.PointNo
+}
+
+// Nearest returns the n points nearest to the location, the nearest first
+func (si *SpatialIndex) Nearest(location Location, n int) []IndexedPoint {
+	if n <= 0 || len(si.points) == 0 {
+		return []IndexedPoint{}
+	}
+	// The search radius is doubled until there are enough points (all
+	// points within the radius are returned, so they include the nearest):
+	radius := si.cellSize * oneDegree
+	for {
+		if radius > 2*math.Pi*earthRadius {
+			// Whole world
+			radius = math.MaxFloat64
+		}
+		result := si.WithinRadius(location, radius)
+		if len(result) >= n {
+			return result[:n]
+		}
+		if len(result) == len(si.points) || radius == math.MaxFloat64 {
+			return result
+		}
+		radius *= 2
+	}
+}
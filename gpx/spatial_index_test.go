@@ -0,0 +1,136 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestIndexGPX() *GPX {
+	points := newTestZigZagPoints(0, 0, 0, 0, 0)
+	return &GPX{
+		Waypoints: []GPXPoint{newTestZigZagPoints(0, 0, 30)[2]},
+		Routes:    []GPXRoute{{Points: newTestZigZagPoints(500, 500)}},
+		Tracks: []GPXTrack{{Segments: []GPXTrackSegment{
+			{Points: points[:2]},
+			{Points: points[2:]},
+		}}},
+	}
+}
+
+func TestSpatialIndexWithinRadius(t *testing.T) {
+	t.Parallel()
+
+	g := newTestIndexGPX()
+	si := NewSpatialIndex(g, 0)
+	assert.Equal(t, 8, si.Len())
+
+	center := g.Tracks[0].Segments[1].Points[0]
+	result := si.WithinRadius(&center, 50)
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, IndexedTrackPoint, result[0].Type)
+	assert.Equal(t, 1, result[0].SegmentNo)
+	assert.Equal(t, 0, result[0].PointNo)
+	assert.Equal(t, 0.0, result[0].Distance)
+	assert.Equal(t, IndexedWaypoint, result[1].Type)
+	assert.InDelta(t, 30, result[1].Distance, 0.5)
+
+	result = si.WithinRadius(&center, 150)
+	assert.Equal(t, 4, len(result))
+
+	tracksOnly := NewSpatialIndex(g, 10, IndexedTrackPoint)
+	assert.Equal(t, 5, tracksOnly.Len())
+	assert.Equal(t, 3, len(tracksOnly.WithinRadius(&center, 150)))
+}
+
+func TestSpatialIndexInBounds(t *testing.T) {
+	t.Parallel()
+
+	g := newTestIndexGPX()
+	si := NewSpatialIndex(g, 0)
+
+	result := si.InBounds(GpxBounds{MinLatitude: 45, MaxLatitude: 45.0015, MinLongitude: 12.9, MaxLongitude: 13.001})
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, IndexedTrackPoint, result[0].Type)
+	assert.Equal(t, 0, result[0].PointNo)
+	assert.Equal(t, 1, result[1].PointNo)
+
+	result = si.InBounds(GpxBounds{MinLatitude: -90, MaxLatitude: 90, MinLongitude: -180, MaxLongitude: 180})
+	assert.Equal(t, 8, len(result))
+	assert.Equal(t, IndexedWaypoint, result[0].Type)
+	assert.Equal(t, IndexedRoutePoint, result[1].Type)
+	assert.Equal(t, IndexedTrackPoint, result[7].Type)
+}
+
+func TestSpatialIndexNearest(t *testing.T) {
+	t.Parallel()
+
+	g := newTestIndexGPX()
+	si := NewSpatialIndex(g, 10)
+
+	far := Point{Latitude: 46, Longitude: 14}
+	result := si.Nearest(&far, 3)
+	assert.Equal(t, 3, len(result))
+	assert.True(t, result[0].Distance <= result[1].Distance)
+	assert.True(t, result[1].Distance <= result[2].Distance)
+
+	assert.Equal(t, 8, len(si.Nearest(&far, 100)))
+	assert.Equal(t, 0, len(si.Nearest(&far, 0)))
+	assert.Equal(t, 0, len(NewSpatialIndex(&GPX{}, 0).Nearest(&far, 1)))
+}
+
+func TestSpatialIndexAntimeridian(t *testing.T) {
+	t.Parallel()
+
+	east := Point{Latitude: 10, Longitude: 179.999}
+	west := Point{Latitude: 10, Longitude: -179.999}
+	g := &GPX{Waypoints: []GPXPoint{{Point: west}, {Point: Point{Latitude: 10, Longitude: 179.98}}}}
+	si := NewSpatialIndex(g, 0)
+
+	result := si.WithinRadius(&east, 1000)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, 0, result[0].PointNo)
+	assert.InDelta(t, 219, result[0].Distance, 1)
+
+	assert.Equal(t, 2, len(si.Nearest(&east, 2)))
+	result = si.Nearest(&east, 1)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, 0, result[0].PointNo)
+
+	result = si.InBounds(GpxBounds{MinLatitude: 9, MaxLatitude: 11, MinLongitude: -180, MaxLongitude: -179})
+	assert.Equal(t, 1, len(result))
+}
+
+func TestSpatialIndexNearestRandom(t *testing.T) {
+	t.Parallel()
+
+	random := rand.New(rand.NewSource(1))
+	seg := GPXTrackSegment{}
+	for i := 0; i < 1000; i++ {
+		seg.Points = append(seg.Points, GPXPoint{Point: Point{Latitude: 45 + random.Float64()/10, Longitude: 13 + random.Float64()/10}})
+	}
+	si := NewSpatialIndex(&GPX{Tracks: []GPXTrack{{Segments: []GPXTrackSegment{seg}}}}, 200)
+
+	for i := 0; i < 20; i++ {
+		location := Point{Latitude: 45 + random.Float64()/5, Longitude: 13 + random.Float64()/5}
+		distances := make([]float64, len(seg.Points))
+		for pointNo := range seg.Points {
+			distances[pointNo] = seg.Points[pointNo].Distance2D(&location)
+		}
+		sort.Float64s(distances)
+
+		result := si.Nearest(&location, 5)
+		assert.Equal(t, 5, len(result))
+		for n := range result {
+			assert.Equal(t, distances[n], result[n].Distance)
+		}
+		assert.Equal(t, len(si.WithinRadius(&location, 500)), sort.SearchFloat64s(distances, 500.0000001))
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"errors"
+	"time"
+)
+
+// trackBoundary is a location on a track between the points pointNo and
+// pointNo+1 of the segment segmentNo (ratio 0 is exactly at pointNo)
+type trackBoundary struct {
+	segmentNo int
+	pointNo   int
+	ratio     float64
+}
+
+// trackPointValue is a (cumulative) value (distance, time, ...) at a track point
+type trackPointValue struct {
+	segmentNo int
+	pointNo   int
+	value     float64
+}
+
+func (trk *GPXTrack) location(b trackBoundary) GPXPoint {
+	seg := &trk.Segments[b.segmentNo]
+	if b.ratio <= 0 || b.pointNo >= len(seg.Points)-1 {
+		return seg.Points[b.pointNo]
+	}
+	return interpolatePoints(&seg.Points[b.pointNo], &seg.Points[b.pointNo+1], b.ratio)
+}
+
+func (trk *GPXTrack) interpolatedLocation(b trackBoundary) InterpolatedLocation {
+	location := newInterpolatedLocation(&trk.Segments[b.segmentNo], b.pointNo, b.ratio)
+	location.SegmentNo = b.segmentNo
+	return location
+}
+
+// distancesFromStart returns the cumulative 2D distance for every track
+// point. Gaps between segments are not counted.
+func (trk *GPXTrack) distancesFromStart() []trackPointValue {
+	result := make([]trackPointValue, 0, trk.GetTrackPointsNo())
+	var fromStart float64
+	for segmentNo, segment := range trk.Segments {
+		for pointNo := range segment.Points {
+			if pointNo > 0 {
+				fromStart += segment.Points[pointNo].Distance2D(&segment.Points[pointNo-1])
+			}
+			result = append(result, trackPointValue{segmentNo: segmentNo, pointNo: pointNo, value: fromStart})
+		}
+	}
+	return result
+}
+
+// secondsFromStart returns the number of seconds from the first track point
+// for every track point with a timestamp
+func (trk *GPXTrack) secondsFromStart() []trackPointValue {
+	result := make([]trackPointValue, 0, trk.GetTrackPointsNo())
+	var start time.Time
+	for segmentNo, segment := range trk.Segments {
+		for pointNo := range segment.Points {
+			point := &segment.Points[pointNo]
+			if !hasTimestamp(point) {
+				continue
+			}
+			if len(result) == 0 {
+				start = point.Timestamp
+			}
+			result = append(result, trackPointValue{segmentNo: segmentNo, pointNo: pointNo, value: point.Timestamp.Sub(start).Seconds()})
+		}
+	}
+	return result
+}
+
+// boundariesEvery returns the track locations where the value crosses every
+// multiple of step (plus the first and last point). Boundaries in a gap
+// between segments are placed at the start of the next segment.
+func boundariesEvery(values []trackPointValue, step float64) []trackBoundary {
+	if len(values) == 0 || step <= 0 {
+		return nil
+	}
+
+	first := values[0]
+	result := []trackBoundary{{segmentNo: first.segmentNo, pointNo: first.pointNo}}
+	appendBoundary := func(b trackBoundary) {
+		if result[len(result)-1] != b {
+			result = append(result, b)
+		}
+	}
+
+	next := first.value + step
+	for i := 1; i < len(values); i++ {
+		previous, current := values[i-1], values[i]
+		for next < current.value-1e-9 {
+			if previous.segmentNo != current.segmentNo || current.pointNo != previous.pointNo+1 {
+				appendBoundary(trackBoundary{segmentNo: current.segmentNo, pointNo: current.pointNo})
+			} else if next >= previous.value {
+				ratio := (next - previous.value) / (current.value - previous.value)
+				appendBoundary(trackBoundary{segmentNo: previous.segmentNo, pointNo: previous.pointNo, ratio: ratio})
+			}
+			next += step
+		}
+	}
+	last := values[len(values)-1]
+	appendBoundary(trackBoundary{segmentNo: last.segmentNo, pointNo: last.pointNo})
+
+	return result
+}
+
+// extractRange returns the parts of track segments between two boundaries
+// with interpolated first and last points
+func (trk *GPXTrack) extractRange(from, to trackBoundary) []GPXTrackSegment {
+	result := make([]GPXTrackSegment, 0)
+	for segmentNo := from.segmentNo; segmentNo <= to.segmentNo && segmentNo < len(trk.Segments); segmentNo++ {
+		seg := &trk.Segments[segmentNo]
+		if len(seg.Points) == 0 {
+			continue
+		}
+		piece := GPXTrackSegment{Extensions: seg.Extensions}
+		firstPointNo := 0
+		if segmentNo == from.segmentNo {
+			piece.Points = append(piece.Points, trk.location(from))
+			firstPointNo = from.pointNo + 1
+		}
+		lastPointNo := len(seg.Points) - 1
+		if segmentNo == to.segmentNo {
+			lastPointNo = to.pointNo
+		}
+		for pointNo := firstPointNo; pointNo <= lastPointNo; pointNo++ {
+			piece.Points = append(piece.Points, seg.Points[pointNo])
+		}
+		if segmentNo == to.segmentNo && to.ratio > 0 {
+			piece.Points = append(piece.Points, trk.location(to))
+		}
+		result = append(result, piece)
+	}
+	return result
+}
+
+// timeWeightedAverage returns the average of the values weighted by the
+// time between points
+func timeWeightedAverage(segments []GPXTrackSegment, value func(*GPXPoint) NullableFloat64) NullableFloat64 {
+	var sum, seconds float64
+	for _, seg := range segments {
+		for pointNo := 1; pointNo < len(seg.Points); pointNo++ {
+			previous, current := &seg.Points[pointNo-1], &seg.Points[pointNo]
+			value1, value2 := value(previous), value(current)
+			dt := current.Timestamp.Sub(previous.Timestamp).Seconds()
+			if value1.Null() || value2.Null() || dt <= 0 {
+				continue
+			}
+			sum += (value1.Value() + value2.Value()) / 2 * dt
+			seconds += dt
+		}
+	}
+	if seconds == 0 {
+		return NullableFloat64{}
+	}
+	return *NewNullableFloat64(sum / seconds)
+}
+
+// ----------------------------------------------------------------------------------------------------
+
+// TrackSplit contains the statistics of a part (lap, split) of a track
+type TrackSplit struct {
+	// Start and End are interpolated locations on the split boundaries
+	Start InterpolatedLocation
+	End   InterpolatedLocation
+	// StartDistance is the 2D distance from the track start in meters
+	StartDistance float64
+	// Distance is the 2D length in meters
+	Distance float64
+	// Duration is the elapsed time in seconds
+	Duration float64
+	// MovingTime in seconds
+	MovingTime float64
+	// Pace in seconds per kilometer (computed from the moving time)
+	Pace     float64
+	Uphill   float64
+	Downhill float64
+	// AverageHeartRate is a time weighted average of heart rate values from the point extensions
+	AverageHeartRate NullableFloat64
+
+	from trackBoundary
+	to   trackBoundary
+}
+
+func (trk *GPXTrack) splitsBetween(boundaries []trackBoundary) []TrackSplit {
+	result := make([]TrackSplit, 0)
+	var fromStart float64
+	for i := 1; i < len(boundaries); i++ {
+		from, to := boundaries[i-1], boundaries[i]
+		pieces := trk.extractRange(from, to)
+
+		split := TrackSplit{
+			Start:         trk.interpolatedLocation(from),
+			End:           trk.interpolatedLocation(to),
+			StartDistance: fromStart,
+			from:          from,
+			to:            to,
+		}
+		for _, piece := range pieces {
+			split.Distance += piece.Length2D()
+			split.MovingTime += piece.MovingData().MovingTime
+			updo := piece.UphillDownhill()
+			split.Uphill += updo.Uphill
+			split.Downhill += updo.Downhill
+		}
+		if hasTimestamp(&split.Start.GPXPoint) && hasTimestamp(&split.End.GPXPoint) {
+			split.Duration = split.End.Timestamp.Sub(split.Start.Timestamp).Seconds()
+		}
+		if split.Distance > 0 {
+			seconds := split.MovingTime
+			if seconds == 0 {
+				seconds = split.Duration
+			}
+			split.Pace = seconds / (split.Distance / 1000)
+		}
+		split.AverageHeartRate = timeWeightedAverage(pieces, (*GPXPoint).HeartRate)
+
+		fromStart += split.Distance
+		result = append(result, split)
+	}
+	return result
+}
+
+// Splits returns the track statistics for every given distance in meters
+// (for example 1000 for kilometer splits). The last split is usually shorter.
+func (trk *GPXTrack) Splits(distance float64) []TrackSplit {
+	return trk.splitsBetween(boundariesEvery(trk.distancesFromStart(), distance))
+}
+
+// SplitsByTime returns the track statistics for every given duration of
+// elapsed time (laps). The last lap is usually shorter.
+func (trk *GPXTrack) SplitsByTime(d time.Duration) []TrackSplit {
+	return trk.splitsBetween(boundariesEvery(trk.secondsFromStart(), d.Seconds()))
+}
+
+// validBoundary checks that the boundary is on the track and at the expected location
+func (trk *GPXTrack) validBoundary(b trackBoundary, expected InterpolatedLocation) bool {
+	if b.segmentNo < 0 || b.segmentNo >= len(trk.Segments) || b.pointNo < 0 || b.pointNo >= len(trk.Segments[b.segmentNo].Points) {
+		return false
+	}
+	location := trk.location(b)
+	return location.Latitude == expected.Latitude && location.Longitude == expected.Longitude
+}
+
+// SplitAtSplits replaces the track segments with one segment per split (and
+// original segment). Interpolated boundary points are added to both
+// neighbouring segments. The splits must be computed on this track (and the
+// track must not be modified since), otherwise an error is returned and the
+// track is not changed.
+func (trk *GPXTrack) SplitAtSplits(splits []TrackSplit) error {
+	if len(splits) == 0 {
+		return nil
+	}
+	for _, split := range splits {
+		if !trk.validBoundary(split.from, split.Start) || !trk.validBoundary(split.to, split.End) {
+			return errors.New("split not computed on this track")
+		}
+	}
+	newSegments := make([]GPXTrackSegment, 0)
+	for _, split := range splits {
+		for _, piece := range trk.extractRange(split.from, split.to) {
+			if len(piece.Points) > 1 {
+				newSegments = append(newSegments, piece)
+			}
+		}
+	}
+	trk.Segments = newSegments
+	return nil
+}
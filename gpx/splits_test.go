@@ -0,0 +1,108 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitsByDistance(t *testing.T) {
+	t.Parallel()
+
+	// 10 points every 100m and 20s (5m/s):
+	seg := newTestSegment(20, 100, 100, 100, 100, 100, 100, 100, 100, 100)
+	for pointNo := range seg.Points {
+		seg.Points[pointNo].Elevation.SetValue(float64(pointNo * 10))
+		seg.Points[pointNo].Extensions = heartRateExtension("150")
+	}
+	track := GPXTrack{}
+	track.AppendSegment(&seg)
+
+	splits := track.Splits(250)
+	assert.Equal(t, 4, len(splits))
+	for splitNo, split := range splits[:3] {
+		assert.InDelta(t, 250, split.Distance, 0.01)
+		assert.InDelta(t, float64(splitNo)*250, split.StartDistance, 0.01)
+		assert.InDelta(t, 50, split.Duration, 0.001)
+		assert.InDelta(t, 50, split.MovingTime, 0.001)
+		assert.InDelta(t, 200, split.Pace, 0.01)
+		assert.InDelta(t, 150, split.AverageHeartRate.Value(), 0.001)
+	}
+	assert.InDelta(t, 150, splits[3].Distance, 0.01)
+	assert.Equal(t, 2, splits[0].End.PointNo)
+	assert.Equal(t, testStartTime.Add(50*time.Second), splits[0].End.Timestamp)
+	assert.InDelta(t, 25, splits[0].End.Elevation.Value(), 0.0001)
+	assert.Equal(t, 0.0, splits[0].Downhill)
+	assert.True(t, splits[0].Uphill > 0)
+}
+
+func TestSplitsByTimeOverSegments(t *testing.T) {
+	t.Parallel()
+
+	seg1 := newTestSegment(10, 100, 100, 100)
+	seg2 := newTestSegment(10, 100, 100, 100)
+	for pointNo := range seg2.Points {
+		seg2.Points[pointNo].Timestamp = seg2.Points[pointNo].Timestamp.Add(time.Minute)
+	}
+	track := GPXTrack{}
+	track.AppendSegment(&seg1)
+	track.AppendSegment(&seg2)
+
+	splits := track.SplitsByTime(25 * time.Second)
+	// 0-25s, 25-30s (end of the first segment), the gap, 60-75s, 75-90s:
+	assert.Equal(t, 4, len(splits))
+	assert.InDelta(t, 25, splits[0].Duration, 0.001)
+	assert.InDelta(t, 250, splits[0].Distance, 0.01)
+	assert.Equal(t, 1, splits[1].End.SegmentNo)
+	assert.Equal(t, 0, splits[1].End.PointNo)
+	assert.InDelta(t, 50, splits[1].Distance, 0.01)
+	assert.InDelta(t, 150, splits[2].Distance, 0.01)
+	assert.InDelta(t, 150, splits[3].Distance, 0.01)
+	assert.True(t, splits[0].AverageHeartRate.Null())
+}
+
+func TestSplitAtSplits(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSegment(20, 100, 100, 100, 100)
+	track := GPXTrack{}
+	track.AppendSegment(&seg)
+	length := track.Length2D()
+
+	assert.Nil(t, track.SplitAtSplits(track.Splits(150)))
+
+	assert.Equal(t, 3, len(track.Segments))
+	assert.Equal(t, 3, len(track.Segments[0].Points))
+	assert.InDelta(t, 150, track.Segments[0].Length2D(), 0.01)
+	assert.InDelta(t, 150, track.Segments[1].Length2D(), 0.01)
+	assert.InDelta(t, 100, track.Segments[2].Length2D(), 0.01)
+	assert.InDelta(t, length, track.Length2D(), 0.01)
+	last := track.Segments[0].Points[2]
+	assert.Equal(t, last.Latitude, track.Segments[1].Points[0].Latitude)
+}
+
+func TestSplitAtSplitsFromOtherTrack(t *testing.T) {
+	t.Parallel()
+
+	long := GPXTrack{Segments: []GPXTrackSegment{newTestSegment(20, 100, 100, 100, 100)}}
+	splits := long.Splits(150)
+
+	short := GPXTrack{Segments: []GPXTrackSegment{newTestSegment(20, 100)}}
+	assert.NotNil(t, short.SplitAtSplits(splits))
+	assert.Equal(t, 1, len(short.Segments))
+	assert.Equal(t, 2, len(short.Segments[0].Points))
+
+	// Same number of points, but different locations:
+	moved := GPXTrack{Segments: []GPXTrackSegment{newTestSegment(20, 50, 50, 50, 50)}}
+	assert.NotNil(t, moved.SplitAtSplits(splits))
+	assert.Equal(t, 1, len(moved.Segments))
+
+	assert.Nil(t, long.SplitAtSplits(splits))
+	assert.Equal(t, 3, len(long.Segments))
+}
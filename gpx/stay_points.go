@@ -0,0 +1,217 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"fmt"
+	"time"
+)
+
+// pointsCentroid returns the average location (elevation is the average of
+// the points with elevation)
+func pointsCentroid(points []GPXPoint) Point {
+	var result Point
+	if len(points) == 0 {
+		return result
+	}
+	var latitude, longitude, elevation float64
+	elevations := 0
+	for pointNo := range points {
+		latitude += points[pointNo].Latitude
+		longitude += points[pointNo].Longitude
+		if points[pointNo].Elevation.NotNull() {
+			elevation += points[pointNo].Elevation.Value()
+			elevations++
+		}
+	}
+	result.Latitude = latitude / float64(len(points))
+	result.Longitude = longitude / float64(len(points))
+	if elevations > 0 {
+		result.Elevation = *NewNullableFloat64(elevation / float64(elevations))
+	}
+	return result
+}
+
+// Visit is a place where the track stayed for some time
+type Visit struct {
+	Centroid  Point
+	Arrival   time.Time
+	Departure time.Time
+	// Positions are the track points of the visit
+	Positions []TrackPosition
+}
+
+// Duration returns the visit duration in seconds
+func (v Visit) Duration() float64 {
+	return v.Departure.Sub(v.Arrival).Seconds()
+}
+
+// StayPoints returns the visits where all the points stay within maxDistance
+// meters from the first visit point for at least minDuration.
+func (seg *GPXTrackSegment) StayPoints(maxDistance float64, minDuration time.Duration) []Visit {
+	result := make([]Visit, 0)
+	for first := 0; first < len(seg.Points); {
+		last := first
+		for last+1 < len(seg.Points) && seg.Points[last+1].Distance2D(&seg.Points[first]) <= maxDistance {
+			last++
+		}
+		arrival, departure := seg.Points[first].Timestamp, seg.Points[last].Timestamp
+		if last == first || departure.Sub(arrival) < minDuration {
+			first++
+			continue
+		}
+		visit := Visit{
+			Centroid:  pointsCentroid(seg.Points[first : last+1]),
+			Arrival:   arrival,
+			Departure: departure,
+		}
+		for pointNo := first; pointNo <= last; pointNo++ {
+			visit.Positions = append(visit.Positions, TrackPosition{Point: seg.Points[pointNo].Point, PointNo: pointNo})
+		}
+		result = append(result, visit)
+		first = last + 1
+	}
+	return result
+}
+
+// StayPoints returns the visits in all track segments
+func (trk *GPXTrack) StayPoints(maxDistance float64, minDuration time.Duration) []Visit {
+	result := make([]Visit, 0)
+	for segmentNo := range trk.Segments {
+		for _, visit := range trk.Segments[segmentNo].StayPoints(maxDistance, minDuration) {
+			for positionNo := range visit.Positions {
+				visit.Positions[positionNo].SegmentNo = segmentNo
+			}
+			result = append(result, visit)
+		}
+	}
+	return result
+}
+
+// StayPoints returns the visits in all tracks
+func (g *GPX) StayPoints(maxDistance float64, minDuration time.Duration) []Visit {
+	result := make([]Visit, 0)
+	for trackNo := range g.Tracks {
+		for _, visit := range g.Tracks[trackNo].StayPoints(maxDistance, minDuration) {
+			for positionNo := range visit.Positions {
+				visit.Positions[positionNo].TrackNo = trackNo
+			}
+			result = append(result, visit)
+		}
+	}
+	return result
+}
+
+// ----------------------------------------------------------------------------------------------------
+
+// Place is a cluster of visits (a frequently visited place)
+type Place struct {
+	Centroid Point
+	Visits   []Visit
+}
+
+// Duration returns the total duration of all visits in seconds
+func (p Place) Duration() float64 {
+	var result float64
+	for _, visit := range p.Visits {
+		result += visit.Duration()
+	}
+	return result
+}
+
+// ToWaypoint creates a waypoint for the place
+func (p Place) ToWaypoint(name string) GPXPoint {
+	var result GPXPoint
+	result.Point = p.Centroid
+	result.Name = name
+	result.Description = fmt.Sprintf("%d visits, %s", len(p.Visits), time.Duration(p.Duration())*time.Second)
+	return result
+}
+
+// ClusterVisits groups the visit centroids with DBSCAN. Visits with at least
+// minVisits visits (including itself) within eps meters are cluster cores,
+// visits not reachable from any core are ignored. Places are ordered by their
+// first core visit.
+func ClusterVisits(visits []Visit, eps float64, minVisits int) []Place {
+	const (
+		unvisited = 0
+		noise     = -1
+	)
+
+	neighbours := func(visitNo int) []int {
+		result := make([]int, 0)
+		for n := range visits {
+			if visits[n].Centroid.Distance2D(&visits[visitNo].Centroid) <= eps {
+				result = append(result, n)
+			}
+		}
+		return result
+	}
+
+	// Cluster numbers start with 1:
+	clusters := make([]int, len(visits))
+	clustersNo := 0
+	for visitNo := range visits {
+		if clusters[visitNo] != unvisited {
+			continue
+		}
+		seeds := neighbours(visitNo)
+		if len(seeds) < minVisits {
+			clusters[visitNo] = noise
+			continue
+		}
+		clustersNo++
+		clusters[visitNo] = clustersNo
+		for len(seeds) > 0 {
+			n := seeds[0]
+			seeds = seeds[1:]
+			if clusters[n] == noise {
+				clusters[n] = clustersNo
+			}
+			if clusters[n] != unvisited {
+				continue
+			}
+			clusters[n] = clustersNo
+			if expanded := neighbours(n); len(expanded) >= minVisits {
+				seeds = append(seeds, expanded...)
+			}
+		}
+	}
+
+	result := make([]Place, clustersNo)
+	for visitNo, cluster := range clusters {
+		if cluster > 0 {
+			result[cluster-1].Visits = append(result[cluster-1].Visits, visits[visitNo])
+		}
+	}
+	for placeNo := range result {
+		centroids := make([]GPXPoint, len(result[placeNo].Visits))
+		for visitNo, visit := range result[placeNo].Visits {
+			centroids[visitNo].Point = visit.Centroid
+		}
+		result[placeNo].Centroid = pointsCentroid(centroids)
+	}
+	return result
+}
+
+// FrequentPlaces finds the stay points in all the GPX documents and clusters them
+func FrequentPlaces(gpxDocs []*GPX, maxDistance float64, minDuration time.Duration, eps float64, minVisits int) []Place {
+	visits := make([]Visit, 0)
+	for _, g := range gpxDocs {
+		visits = append(visits, g.StayPoints(maxDistance, minDuration)...)
+	}
+	return ClusterVisits(visits, eps, minVisits)
+}
+
+// PlacesToGPX creates a GPX with a waypoint for every place
+func PlacesToGPX(places []Place) *GPX {
+	result := new(GPX)
+	for placeNo, place := range places {
+		waypoint := place.ToWaypoint(fmt.Sprintf("Place %d", placeNo+1))
+		result.AppendWaypoint(&waypoint)
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStayPoints(t *testing.T) {
+	t.Parallel()
+
+	// Driving, 5 minutes around one place, driving, a short stop, driving:
+	seg := newTestSegment(60, 1000, 10, -20, 10, 5, 10, 1000, 5, 1000)
+	visits := seg.StayPoints(50, 3*time.Minute)
+	if assert.Equal(t, 1, len(visits)) {
+		visit := visits[0]
+		assert.Equal(t, testStartTime.Add(time.Minute), visit.Arrival)
+		assert.Equal(t, testStartTime.Add(6*time.Minute), visit.Departure)
+		assert.InDelta(t, 300, visit.Duration(), 0.001)
+		if assert.Equal(t, 6, len(visit.Positions)) {
+			assert.Equal(t, 1, visit.Positions[0].PointNo)
+			assert.Equal(t, 6, visit.Positions[5].PointNo)
+		}
+		assert.InDelta(t, 45+(1000+1010+990+1000+1005+1015)/6.0/oneDegree, visit.Centroid.Latitude, 0.000001)
+		assert.InDelta(t, 100, visit.Centroid.Elevation.Value(), 0.000001)
+	}
+
+	assert.Equal(t, 2, len(seg.StayPoints(50, time.Minute)))
+
+	g := GPX{}
+	g.AppendTrack(&GPXTrack{})
+	g.AppendTrack(&GPXTrack{})
+	g.AppendSegment(&seg)
+	visits = g.StayPoints(50, 3*time.Minute)
+	if assert.Equal(t, 1, len(visits)) {
+		assert.Equal(t, 1, visits[0].Positions[0].TrackNo)
+		assert.Equal(t, 0, visits[0].Positions[0].SegmentNo)
+	}
+}
+
+func TestClusterVisits(t *testing.T) {
+	t.Parallel()
+
+	visit := func(latitude, longitude float64) Visit {
+		return Visit{
+			Centroid:  Point{Latitude: latitude, Longitude: longitude},
+			Arrival:   testStartTime,
+			Departure: testStartTime.Add(10 * time.Minute),
+		}
+	}
+	visits := []Visit{
+		visit(45, 13),
+		visit(46, 14),
+		visit(45.0001, 13),
+		visit(47, 15),
+		visit(45.0002, 13),
+		visit(46.0001, 14),
+		visit(45.0003, 13),
+	}
+
+	places := ClusterVisits(visits, 20, 2)
+	if assert.Equal(t, 2, len(places)) {
+		assert.Equal(t, 4, len(places[0].Visits))
+		assert.InDelta(t, 45.00015, places[0].Centroid.Latitude, 0.000001)
+		assert.InDelta(t, 2400, places[0].Duration(), 0.001)
+		assert.Equal(t, 2, len(places[1].Visits))
+	}
+
+	assert.Equal(t, 1, len(ClusterVisits(visits, 20, 3)))
+	assert.Equal(t, 0, len(ClusterVisits(visits, 5, 2)))
+
+	g := PlacesToGPX(places)
+	if assert.Equal(t, 2, len(g.Waypoints)) {
+		assert.Equal(t, "Place 1", g.Waypoints[0].Name)
+		assert.Equal(t, "4 visits, 40m0s", g.Waypoints[0].Description)
+		assert.InDelta(t, 46.00005, g.Waypoints[1].Latitude, 0.000001)
+	}
+}
+
+func TestFrequentPlaces(t *testing.T) {
+	t.Parallel()
+
+	seg1 := newTestSegment(60, 1000, 0, 0, 1000)
+	seg2 := newTestSegment(60, 500, 500, 0, 0, 1000)
+	g1, g2 := GPX{}, GPX{}
+	g1.AppendSegment(&seg1)
+	g2.AppendSegment(&seg2)
+
+	places := FrequentPlaces([]*GPX{&g1, &g2}, 20, 2*time.Minute, 20, 2)
+	if assert.Equal(t, 1, len(places)) {
+		assert.Equal(t, 2, len(places[0].Visits))
+		assert.InDelta(t, 45+1000/oneDegree, places[0].Centroid.Latitude, 0.000001)
+	}
+}
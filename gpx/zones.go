@@ -0,0 +1,255 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"time"
+)
+
+// maxSensorGap is the longest interval between points where the sensor
+// value is considered valid (longer intervals are pauses)
+const maxSensorGap = 30 * time.Second
+
+// Zone is a heart rate (bpm) or power (watts) range [From, To)
+type Zone struct {
+	Name string
+	From float64
+	To   float64
+}
+
+func zonesFromPercentages(reference float64, names []string, percentages []float64) []Zone {
+	result := make([]Zone, len(names))
+	for zoneNo := range result {
+		result[zoneNo].Name = names[zoneNo]
+		if zoneNo > 0 {
+			result[zoneNo].From = reference * percentages[zoneNo-1] / 100
+		}
+		result[zoneNo].To = math.Inf(1)
+		if zoneNo < len(percentages) {
+			result[zoneNo].To = reference * percentages[zoneNo] / 100
+		}
+	}
+	return result
+}
+
+// HeartRateZonesFromMax returns the 5 zones with 60, 70, 80 and 90% of the maximum heart rate bounds
+func HeartRateZonesFromMax(maxHeartRate float64) []Zone {
+	return zonesFromPercentages(maxHeartRate, []string{"Z1", "Z2", "Z3", "Z4", "Z5"}, []float64{60, 70, 80, 90})
+}
+
+// HeartRateZonesFromLTHR returns the Joe Friel lactate threshold heart rate zones
+func HeartRateZonesFromLTHR(lthr float64) []Zone {
+	return zonesFromPercentages(lthr, []string{"Z1", "Z2", "Z3", "Z4", "Z5a", "Z5b", "Z5c"}, []float64{85, 90, 95, 100, 103, 107})
+}
+
+// PowerZonesFromFTP returns the Andrew Coggan functional threshold power zones
+func PowerZonesFromFTP(ftp float64) []Zone {
+	return zonesFromPercentages(ftp, []string{"Z1", "Z2", "Z3", "Z4", "Z5", "Z6", "Z7"}, []float64{56, 76, 91, 106, 121, 151})
+}
+
+// ZoneTime is the time spent in a zone
+type ZoneTime struct {
+	Zone
+	Seconds float64
+}
+
+// sensorIntervals calls f for every interval between two points (not longer
+// than maxSensorGap) with the sensor value at the interval start
+func sensorIntervals(segments []GPXTrackSegment, value func(*GPXPoint) NullableFloat64, f func(value, seconds float64)) {
+	for segmentNo := range segments {
+		points := segments[segmentNo].Points
+		for pointNo := 1; pointNo < len(points); pointNo++ {
+			previous := &points[pointNo-1]
+			dt := points[pointNo].Timestamp.Sub(previous.Timestamp)
+			if !hasTimestamp(previous) || dt <= 0 || dt > maxSensorGap {
+				continue
+			}
+			if v := value(previous); v.NotNull() {
+				f(v.Value(), dt.Seconds())
+			}
+		}
+	}
+}
+
+func timeInZones(segments []GPXTrackSegment, zones []Zone, value func(*GPXPoint) NullableFloat64) []ZoneTime {
+	result := make([]ZoneTime, len(zones))
+	for zoneNo := range zones {
+		result[zoneNo].Zone = zones[zoneNo]
+	}
+	sensorIntervals(segments, value, func(v, seconds float64) {
+		for zoneNo := range result {
+			if result[zoneNo].From <= v && v < result[zoneNo].To {
+				result[zoneNo].Seconds += seconds
+				return
+			}
+		}
+	})
+	return result
+}
+
+// TimeInHeartRateZones returns the seconds spent in every heart rate zone
+func (seg *GPXTrackSegment) TimeInHeartRateZones(zones []Zone) []ZoneTime {
+	return timeInZones([]GPXTrackSegment{*seg}, zones, (*GPXPoint).HeartRate)
+}
+
+// TimeInPowerZones returns the seconds spent in every power zone
+func (seg *GPXTrackSegment) TimeInPowerZones(zones []Zone) []ZoneTime {
+	return timeInZones([]GPXTrackSegment{*seg}, zones, (*GPXPoint).Power)
+}
+
+// TimeInHeartRateZones returns the seconds spent in every heart rate zone
+func (trk *GPXTrack) TimeInHeartRateZones(zones []Zone) []ZoneTime {
+	return timeInZones(trk.Segments, zones, (*GPXPoint).HeartRate)
+}
+
+// TimeInPowerZones returns the seconds spent in every power zone
+func (trk *GPXTrack) TimeInPowerZones(zones []Zone) []ZoneTime {
+	return timeInZones(trk.Segments, zones, (*GPXPoint).Power)
+}
+
+// TimeInHeartRateZones returns the seconds spent in every heart rate zone
+func (g *GPX) TimeInHeartRateZones(zones []Zone) []ZoneTime {
+	return timeInZones(g.allSegments(), zones, (*GPXPoint).HeartRate)
+}
+
+// TimeInPowerZones returns the seconds spent in every power zone
+func (g *GPX) TimeInPowerZones(zones []Zone) []ZoneTime {
+	return timeInZones(g.allSegments(), zones, (*GPXPoint).Power)
+}
+
+func (g *GPX) allSegments() []GPXTrackSegment {
+	result := make([]GPXTrackSegment, 0)
+	for _, trk := range g.Tracks {
+		result = append(result, trk.Segments...)
+	}
+	return result
+}
+
+// ----------------------------------------------------------------------------------------------------
+
+// Athlete contains the values needed for training load metrics
+type Athlete struct {
+	// FTP is the functional threshold power in watts
+	FTP              float64
+	MaxHeartRate     float64
+	RestingHeartRate float64
+	Female           bool
+}
+
+// TrainingLoad contains the training load metrics (null if there is not enough data)
+type TrainingLoad struct {
+	// NormalizedPower in watts
+	NormalizedPower NullableFloat64
+	// IntensityFactor is the normalized power divided by FTP
+	IntensityFactor NullableFloat64
+	// TSS is the training stress score
+	TSS NullableFloat64
+	// TRIMP is the Banister training impulse
+	TRIMP NullableFloat64
+}
+
+// normalizedPower returns the normalized power and the duration of the power
+// data (in seconds). The power is resampled to 1 second intervals, the 30
+// second rolling average restarts after every gap.
+func normalizedPower(segments []GPXTrackSegment) (NullableFloat64, float64) {
+	const window = 30
+
+	var sum4 float64
+	var count, seconds int
+	var rolling []float64
+	var rollingSum float64
+	lastEnd := time.Time{}
+
+	for segmentNo := range segments {
+		points := segments[segmentNo].Points
+		for pointNo := 1; pointNo < len(points); pointNo++ {
+			previous := &points[pointNo-1]
+			dt := points[pointNo].Timestamp.Sub(previous.Timestamp)
+			power := previous.Power()
+			if !hasTimestamp(previous) || dt <= 0 || dt > maxSensorGap || power.Null() {
+				continue
+			}
+			if !previous.Timestamp.Equal(lastEnd) {
+				rolling, rollingSum = rolling[:0], 0
+			}
+			lastEnd = points[pointNo].Timestamp
+			for n := 0; n < int(math.Round(dt.Seconds())); n++ {
+				rolling = append(rolling, power.Value())
+				rollingSum += power.Value()
+				if len(rolling) > window {
+					rollingSum -= rolling[0]
+					rolling = rolling[1:]
+				}
+				if len(rolling) == window {
+					sum4 += math.Pow(rollingSum/window, 4)
+					count++
+				}
+				seconds++
+			}
+		}
+	}
+
+	if count == 0 {
+		return NullableFloat64{}, float64(seconds)
+	}
+	return *NewNullableFloat64(math.Pow(sum4/float64(count), 0.25)), float64(seconds)
+}
+
+// trimp returns the Banister TRIMP, the heart rate reserve weighted by
+// 0.64*e^(1.92*reserve) (1.67 for women) for every minute
+func trimp(segments []GPXTrackSegment, athlete Athlete) NullableFloat64 {
+	if athlete.MaxHeartRate <= athlete.RestingHeartRate {
+		return NullableFloat64{}
+	}
+	factor := 1.92
+	if athlete.Female {
+		factor = 1.67
+	}
+	var result float64
+	found := false
+	sensorIntervals(segments, (*GPXPoint).HeartRate, func(hr, seconds float64) {
+		reserve := (hr - athlete.RestingHeartRate) / (athlete.MaxHeartRate - athlete.RestingHeartRate)
+		reserve = math.Max(0, math.Min(1, reserve))
+		result += seconds / 60 * reserve * 0.64 * math.Exp(factor*reserve)
+		found = true
+	})
+	if !found {
+		return NullableFloat64{}
+	}
+	return *NewNullableFloat64(result)
+}
+
+func trainingLoad(segments []GPXTrackSegment, athlete Athlete) TrainingLoad {
+	var result TrainingLoad
+	var seconds float64
+	result.NormalizedPower, seconds = normalizedPower(segments)
+	if result.NormalizedPower.NotNull() && athlete.FTP > 0 {
+		intensity := result.NormalizedPower.Value() / athlete.FTP
+		result.IntensityFactor = *NewNullableFloat64(intensity)
+		result.TSS = *NewNullableFloat64(seconds * result.NormalizedPower.Value() * intensity / (athlete.FTP * 3600) * 100)
+	}
+	result.TRIMP = trimp(segments, athlete)
+	return result
+}
+
+// TrainingLoad returns the normalized power, intensity factor, TSS and TRIMP
+// of a GPX segment. Intervals longer than 30 seconds are pauses.
+func (seg *GPXTrackSegment) TrainingLoad(athlete Athlete) TrainingLoad {
+	return trainingLoad([]GPXTrackSegment{*seg}, athlete)
+}
+
+// TrainingLoad returns the normalized power, intensity factor, TSS and TRIMP
+// of a GPX track. Intervals longer than 30 seconds are pauses.
+func (trk *GPXTrack) TrainingLoad(athlete Athlete) TrainingLoad {
+	return trainingLoad(trk.Segments, athlete)
+}
+
+// TrainingLoad returns the normalized power, intensity factor, TSS and TRIMP
+// of all tracks. Intervals longer than 30 seconds are pauses.
+func (g *GPX) TrainingLoad(athlete Athlete) TrainingLoad {
+	return trainingLoad(g.allSegments(), athlete)
+}
@@ -0,0 +1,106 @@
+// Copyright 2013, 2014 Peter Vasil, Tomo Krajina. All
+// rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gpx
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZoneDefinitions(t *testing.T) {
+	t.Parallel()
+
+	zones := HeartRateZonesFromMax(200)
+	assert.Equal(t, 5, len(zones))
+	assert.Equal(t, Zone{Name: "Z1", From: 0, To: 120}, zones[0])
+	assert.Equal(t, Zone{Name: "Z3", From: 140, To: 160}, zones[2])
+	assert.True(t, math.IsInf(zones[4].To, 1))
+
+	zones = HeartRateZonesFromLTHR(160)
+	assert.Equal(t, 7, len(zones))
+	assert.Equal(t, "Z5a", zones[4].Name)
+	assert.InDelta(t, 160, zones[4].From, 0.001)
+
+	zones = PowerZonesFromFTP(200)
+	assert.Equal(t, 7, len(zones))
+	assert.InDelta(t, 182, zones[3].From, 0.001)
+	assert.InDelta(t, 212, zones[3].To, 0.001)
+}
+
+func newTestSensorSegment(secondsBetween int, heartRates, powers []float64) GPXTrackSegment {
+	seg := newTestSegment(secondsBetween, make([]float64, len(heartRates)-1)...)
+	for pointNo := range seg.Points {
+		seg.Points[pointNo].SetHeartRate(heartRates[pointNo])
+		seg.Points[pointNo].SetPower(powers[pointNo])
+	}
+	return seg
+}
+
+func TestTimeInZones(t *testing.T) {
+	t.Parallel()
+
+	seg := newTestSensorSegment(10, []float64{100, 130, 150, 190, 190}, []float64{100, 150, 200, 350, 0})
+	// A pause (not counted):
+	seg.Points[3].Timestamp = seg.Points[3].Timestamp.Add(time.Hour)
+	seg.Points[4].Timestamp = seg.Points[4].Timestamp.Add(time.Hour)
+
+	hrZones := seg.TimeInHeartRateZones(HeartRateZonesFromMax(200))
+	seconds := make([]float64, len(hrZones))
+	for zoneNo := range hrZones {
+		seconds[zoneNo] = hrZones[zoneNo].Seconds
+	}
+	assert.Equal(t, []float64{10, 10, 0, 0, 10}, seconds)
+	assert.Equal(t, "Z1", hrZones[0].Name)
+
+	powerZones := seg.TimeInPowerZones(PowerZonesFromFTP(200))
+	assert.Equal(t, 10.0, powerZones[0].Seconds)
+	assert.Equal(t, 10.0, powerZones[1].Seconds)
+	assert.Equal(t, 0.0, powerZones[3].Seconds)
+	assert.Equal(t, 10.0, powerZones[6].Seconds)
+
+	g := GPX{}
+	g.AppendSegment(&seg)
+	g.AppendSegment(&seg)
+	assert.Equal(t, 20.0, g.TimeInHeartRateZones(HeartRateZonesFromMax(200))[4].Seconds)
+	assert.Equal(t, 20.0, g.Tracks[0].TimeInPowerZones(PowerZonesFromFTP(200))[6].Seconds)
+}
+
+func TestTrainingLoad(t *testing.T) {
+	t.Parallel()
+
+	// One hour at FTP:
+	heartRates, powers := make([]float64, 361), make([]float64, 361)
+	for n := range heartRates {
+		heartRates[n], powers[n] = 160, 250
+	}
+	seg := newTestSensorSegment(10, heartRates, powers)
+	load := seg.TrainingLoad(Athlete{FTP: 250, MaxHeartRate: 200, RestingHeartRate: 50})
+	assert.InDelta(t, 250, load.NormalizedPower.Value(), 0.001)
+	assert.InDelta(t, 1, load.IntensityFactor.Value(), 0.001)
+	assert.InDelta(t, 100, load.TSS.Value(), 0.001)
+	reserve := (160.0 - 50) / 150
+	assert.InDelta(t, 60*reserve*0.64*math.Exp(1.92*reserve), load.TRIMP.Value(), 0.001)
+
+	female := seg.TrainingLoad(Athlete{MaxHeartRate: 200, RestingHeartRate: 50, Female: true})
+	assert.True(t, female.TSS.Null())
+	assert.True(t, female.TRIMP.Value() < load.TRIMP.Value())
+
+	// Variable power (the normalized power is bigger than the average):
+	for pointNo := range seg.Points {
+		seg.Points[pointNo].SetPower(float64(pointNo/6%2) * 500)
+	}
+	load = seg.TrainingLoad(Athlete{FTP: 250})
+	assert.True(t, load.NormalizedPower.Value() > 260)
+	assert.True(t, load.TRIMP.Null())
+
+	// Too short:
+	short := newTestSensorSegment(10, []float64{150, 150}, []float64{200, 200})
+	load = short.TrainingLoad(Athlete{FTP: 250})
+	assert.True(t, load.NormalizedPower.Null())
+}
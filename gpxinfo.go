@@ -8,11 +8,24 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/tkrajina/gpxgo/gpx"
 )
 
+var (
+	photosDir     = flag.String("photos", "", "Geotag the JPEG photos in this directory")
+	photosOffset  = flag.Duration("photos-offset", 0, "Camera clock offset added to photo times (for example -1m30s)")
+	photosTZ      = flag.String("photos-tz", "", "Camera time zone (for example Europe/Ljubljana), used when photos don't contain time zone information")
+	photosMaxGap  = flag.Duration("photos-max-gap", time.Minute, "Maximum time between a photo and the nearest track point")
+	photosWrite   = flag.Bool("photos-write", false, "Write the GPS location into the photos' EXIF data")
+	photosGpxFile = flag.String("photos-gpx", "", "Save a GPX file with a waypoint for every geotagged photo")
+)
+
 func main() {
 	flag.Parse()
 
@@ -30,9 +43,82 @@ func main() {
 		return
 	}
 
+	if len(*photosDir) > 0 {
+		geotagPhotos(gpxFile)
+		return
+	}
+
 	gpxPath, _ := filepath.Abs(gpxFileArg)
 
 	fmt.Print("File: ", gpxPath, "\n")
 
 	fmt.Println(gpxFile.GetGpxInfo())
 }
+
+func geotagPhotos(gpxFile *gpx.GPX) {
+	opts := gpx.GeotagOptions{CameraOffset: *photosOffset, MaxGap: *photosMaxGap}
+	var cameraLocation *time.Location
+	if len(*photosTZ) > 0 {
+		var err error
+		cameraLocation, err = time.LoadLocation(*photosTZ)
+		if err != nil {
+			fmt.Println("Invalid time zone: ", err)
+			return
+		}
+	}
+
+	files, err := ioutil.ReadDir(*photosDir)
+	if err != nil {
+		fmt.Println("Error reading photos: ", err)
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	waypoints := gpx.GPX{Creator: gpxFile.Creator}
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file.Name()))
+		if file.IsDir() || (ext != ".jpg" && ext != ".jpeg") {
+			continue
+		}
+		fileName := filepath.Join(*photosDir, file.Name())
+
+		exif, err := gpx.ReadJPEGExifFile(fileName)
+		if err != nil {
+			fmt.Printf("%s: %s\n", file.Name(), err.Error())
+			continue
+		}
+		if exif.Time.Null() {
+			fmt.Printf("%s: no time\n", file.Name())
+			continue
+		}
+
+		photoOpts := opts
+		if !exif.HasTimeZone {
+			photoOpts.CameraLocation = cameraLocation
+		}
+		location := gpxFile.GeotagTime(exif.Time.Value(), photoOpts)
+		if !location.Found {
+			fmt.Printf("%s: %s not on track\n", file.Name(), location.Time)
+			continue
+		}
+		fmt.Printf("%s: %s %f, %f\n", file.Name(), location.Time, location.Location.Latitude, location.Location.Longitude)
+
+		waypoint := location.ToWaypoint(file.Name())
+		waypoints.AppendWaypoint(&waypoint)
+		if *photosWrite {
+			if err := gpx.WriteJPEGLocationFile(fileName, waypoint); err != nil {
+				fmt.Printf("%s: error writing location: %s\n", file.Name(), err.Error())
+			}
+		}
+	}
+
+	if len(*photosGpxFile) > 0 {
+		xml, err := waypoints.ToXml(gpx.ToXmlParams{Version: "1.1", Indent: true})
+		if err == nil {
+			err = ioutil.WriteFile(*photosGpxFile, xml, 0644)
+		}
+		if err != nil {
+			fmt.Println("Error saving waypoints: ", err)
+		}
+	}
+}